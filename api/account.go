@@ -1,15 +1,68 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
 	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
 	"net/http"
+	"time"
 )
 
+type accountResponse struct {
+	ID             int64            `json:"id"`
+	Owner          string           `json:"owner"`
+	Balance        util.Money       `json:"balance"`
+	Currency       string           `json:"currency"`
+	CreatedAt      time.Time        `json:"created_at"`
+	OverdraftLimit util.Money       `json:"overdraft_limit"`
+	Status         db.AccountStatus `json:"status"`
+}
+
+func newAccountResponse(account db.Account, currency util.Currency) accountResponse {
+	return accountResponse{
+		ID:             account.ID,
+		Owner:          account.Owner,
+		Balance:        util.NewMoney(account.Balance, currency),
+		Currency:       account.Currency,
+		CreatedAt:      account.CreatedAt,
+		OverdraftLimit: util.NewMoney(account.OverdraftLimit, currency),
+		Status:         account.Status,
+	}
+}
+
+// accountResponse formats the account amounts in its currency
+func (s *Server) accountResponse(c *gin.Context, account db.Account) (accountResponse, bool) {
+	currency, ok := s.currency(c, account.Currency)
+	if !ok {
+		return accountResponse{}, false
+	}
+
+	return newAccountResponse(account, currency), true
+}
+
+// accountsResponse formats a page of accounts that may hold different currencies
+func (s *Server) accountsResponse(c *gin.Context, accounts []db.Account) ([]accountResponse, bool) {
+	rsp := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		var ok bool
+		if rsp[i], ok = s.accountResponse(c, account); !ok {
+			return nil, false
+		}
+	}
+
+	return rsp, true
+}
+
+// writeAccount replies with the account formatted in its currency
+func (s *Server) writeAccount(c *gin.Context, account db.Account) {
+	if rsp, ok := s.accountResponse(c, account); ok {
+		c.JSON(http.StatusOK, rsp)
+	}
+}
+
 type createAccountRequest struct {
 	Currency string `json:"currency" binding:"required,currency"`
 }
@@ -41,40 +94,12 @@ func (s *Server) createAccount(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, account)
-}
-
-type getAccountRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"`
+	s.writeAccount(c, account)
 }
 
 func (s *Server) getAccount(c *gin.Context) {
-	var req getAccountRequest
-
-	if err := c.ShouldBindUri(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
-		return
-	}
-
-	account, err := s.store.GetAccount(c, req.ID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
-	if account.Owner != authPayload.Username {
-		err := errors.New("account doesn't belong to the authenticated user")
-		c.JSON(http.StatusForbidden, errorResponse(err))
-		return
-	}
-
-	c.JSON(http.StatusOK, account)
+	account := c.MustGet(accountKey).(db.Account)
+	s.writeAccount(c, account)
 }
 
 type listAccountRequest struct {
@@ -103,22 +128,21 @@ func (s *Server) listAccount(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, accounts)
-}
-
-type updateAccountParam struct {
-	ID int64 `uri:"id" binding:"required,min=1"`
+	if rsp, ok := s.accountsResponse(c, accounts); ok {
+		c.JSON(http.StatusOK, rsp)
+	}
 }
 
 type updateAccountRequestBody struct {
-	Balance int64 `json:"balance" binding:"required"`
+	Balance string `json:"balance" binding:"required"`
 }
 
+// updateAccount sets the balance by posting the difference as an adjustment, so it is reserved for admins
 func (s *Server) updateAccount(c *gin.Context) {
-	var param updateAccountParam
+	var uri resourceURI
 	var req updateAccountRequestBody
 
-	if err := c.ShouldBindUri(&param); err != nil {
+	if err := c.ShouldBindUri(&uri); err != nil {
 		c.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
@@ -128,37 +152,50 @@ func (s *Server) updateAccount(c *gin.Context) {
 		return
 	}
 
-	arg := db.UpdateAccountParams{ID: param.ID, Balance: req.Balance}
-	account, err := s.store.UpdateAccount(c, arg)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
+	account, valid := s.findAccount(c, uri.ID)
+	if !valid {
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+	currency, ok := s.currency(c, account.Currency)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, account)
-}
+	balance, ok := parseAmount(c, req.Balance, currency)
+	if !ok {
+		return
+	}
+
+	arg := db.BalanceTxParams{AccountID: uri.ID, Amount: balance.Amount}
+	result, err := s.store.SetBalanceTx(c, arg)
+	if err != nil {
+		adminAccountError(c, err)
+		return
+	}
 
-type deleteAccountRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"`
+	c.JSON(http.StatusOK, newAccountResponse(result.Account, currency))
 }
 
-func (s *Server) deleteAccount(c *gin.Context) {
-	var req deleteAccountRequest
+// closeAccount closes the account instead of deleting it, so its entries and transfers stay queryable
+func (s *Server) closeAccount(c *gin.Context) {
+	account := c.MustGet(accountKey).(db.Account)
 
-	if err := c.ShouldBindUri(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
-		return
-	}
+	account, err := s.store.CloseAccountTx(c, account.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotEmpty) || errors.Is(err, db.ErrAccountNotActive) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrTxRetriesExhausted) {
+			c.JSON(http.StatusServiceUnavailable, errorResponse(err))
+			return
+		}
 
-	if err := s.store.DeleteAccount(c, req.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	c.Status(http.StatusOK)
+	s.writeAccount(c, account)
 }
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"net/http"
+)
+
+// listAllAccounts lists the accounts of every user
+func (s *Server) listAllAccounts(c *gin.Context) {
+	var req listAccountRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListAllAccountsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	accounts, err := s.store.ListAllAccounts(c, arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if rsp, ok := s.accountsResponse(c, accounts); ok {
+		c.JSON(http.StatusOK, rsp)
+	}
+}
+
+type setAccountStatusRequest struct {
+	Status db.AccountStatus `json:"status" binding:"required,oneof=active frozen"`
+}
+
+// setAccountStatus freezes or reactivates an account, closing stays with the owner
+func (s *Server) setAccountStatus(c *gin.Context) {
+	var uri resourceURI
+	var req setAccountStatusRequest
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.SetAccountStatusTxParams{AccountID: uri.ID, Status: req.Status}
+	account, err := s.store.SetAccountStatusTx(c, arg)
+	if err != nil {
+		adminAccountError(c, err)
+		return
+	}
+
+	s.writeAccount(c, account)
+}
+
+type adjustBalanceRequest struct {
+	Amount string `json:"amount" binding:"required"`
+}
+
+// adjustBalance books a manual correction, the amount is negative to take money from the account
+func (s *Server) adjustBalance(c *gin.Context) {
+	var uri resourceURI
+	var req adjustBalanceRequest
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := s.findAccount(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	currency, ok := s.currency(c, account.Currency)
+	if !ok {
+		return
+	}
+
+	amount, ok := parseAmount(c, req.Amount, currency)
+	if !ok {
+		return
+	}
+
+	if amount.Amount == 0 {
+		err := errors.New("amount must not be zero")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.BalanceTxParams{AccountID: uri.ID, Amount: amount.Amount}
+	result, err := s.store.AdjustBalanceTx(c, arg)
+	if err != nil {
+		adminAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newBalanceTxResponse(result, currency))
+}
+
+// getTxStats reports how often transactions were retried after serialization failures and deadlocks
+func (s *Server) getTxStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.store.TxStats())
+}
+
+func adminAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, errorResponse(err))
+	case errors.Is(err, db.ErrAccountClosed) || errors.Is(err, db.ErrSystemAccount) ||
+		errors.Is(err, db.ErrBalanceOverflow):
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+	case errors.Is(err, db.ErrTxRetriesExhausted):
+		c.JSON(http.StatusServiceUnavailable, errorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
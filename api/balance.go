@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/util"
+	"net/http"
+)
+
+type balanceRequest struct {
+	Amount   string `json:"amount" binding:"required"`
+	Currency string `json:"currency" binding:"required,currency"`
+}
+
+type balanceTxResponse struct {
+	Account accountResponse `json:"account"`
+	Entry   entryResponse   `json:"entry"`
+}
+
+func newBalanceTxResponse(result db.BalanceTxResult, currency util.Currency) balanceTxResponse {
+	return balanceTxResponse{
+		Account: newAccountResponse(result.Account, currency),
+		Entry:   newEntryResponse(result.Entry, currency),
+	}
+}
+
+func (s *Server) createDeposit(c *gin.Context) {
+	s.changeBalance(c, s.store.DepositTx)
+}
+
+func (s *Server) createWithdrawal(c *gin.Context) {
+	s.changeBalance(c, s.store.WithdrawTx)
+}
+
+func (s *Server) changeBalance(
+	c *gin.Context,
+	tx func(ctx context.Context, arg db.BalanceTxParams) (db.BalanceTxResult, error),
+) {
+	var req balanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account := c.MustGet(accountKey).(db.Account)
+	if account.Currency != req.Currency {
+		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, req.Currency)
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	currency, ok := s.currency(c, account.Currency)
+	if !ok {
+		return
+	}
+
+	amount, ok := parsePositiveAmount(c, req.Amount, currency)
+	if !ok {
+		return
+	}
+
+	arg := db.BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    amount.Amount,
+	}
+
+	result, err := tx(c, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrAccountNotActive) ||
+			errors.Is(err, db.ErrBalanceOverflow) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrTxRetriesExhausted) {
+			c.JSON(http.StatusServiceUnavailable, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, newBalanceTxResponse(result, currency))
+}
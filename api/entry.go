@@ -0,0 +1,96 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/util"
+	"net/http"
+	"time"
+)
+
+// entryResponse only has a transfer and a counterparty when the entry was made by a transfer
+type entryResponse struct {
+	ID                    int64        `json:"id"`
+	AccountID             int64        `json:"account_id"`
+	Amount                util.Money   `json:"amount"`
+	Type                  db.EntryType `json:"type"`
+	TransferID            int64        `json:"transfer_id,omitempty"`
+	CounterpartyAccountID int64        `json:"counterparty_account_id,omitempty"`
+	CreatedAt             time.Time    `json:"created_at"`
+}
+
+func newEntryResponse(entry db.Entry, currency util.Currency) entryResponse {
+	return entryResponse{
+		ID:         entry.ID,
+		AccountID:  entry.AccountID,
+		Amount:     util.NewMoney(entry.Amount, currency),
+		Type:       entry.EntryType,
+		TransferID: entry.TransferID.Int64,
+		CreatedAt:  entry.CreatedAt,
+	}
+}
+
+type listEntriesRequest struct {
+	Cursor   int64     `form:"cursor" binding:"min=0"`
+	PageSize int32     `form:"page_size" binding:"required,min=5,max=100"`
+	From     time.Time `form:"from"`
+	To       time.Time `form:"to"`
+}
+
+type listEntriesResponse struct {
+	Entries    []entryResponse `json:"entries"`
+	NextCursor int64           `json:"next_cursor,omitempty"`
+}
+
+// listEntries pages through the account entries by id, pass next_cursor back as cursor to get the next page
+func (s *Server) listEntries(c *gin.Context) {
+	var req listEntriesRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		err := errors.New("from must be before to")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account := c.MustGet(accountKey).(db.Account)
+	arg := db.ListEntriesAfterParams{
+		AccountID: account.ID,
+		AfterID:   req.Cursor,
+		FromTime:  sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		ToTime:    sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		// one extra row tells whether there is a next page
+		PageSize: req.PageSize + 1,
+	}
+
+	entries, err := s.store.ListEntriesAfter(c, arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	currency, ok := s.currency(c, account.Currency)
+	if !ok {
+		return
+	}
+
+	var rsp listEntriesResponse
+	if len(entries) > int(req.PageSize) {
+		entries = entries[:req.PageSize]
+		rsp.NextCursor = entries[req.PageSize-1].Entry.ID
+	}
+
+	rsp.Entries = make([]entryResponse, len(entries))
+	for i, row := range entries {
+		rsp.Entries[i] = newEntryResponse(row.Entry, currency)
+		rsp.Entries[i].CounterpartyAccountID = row.CounterpartyAccountID
+	}
+
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vadym-98/simple_bank/db/mock"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
+	"github.com/vadym-98/simple_bank/util/faker"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestListEntriesAPI(t *testing.T) {
+	user := faker.NewUser().Get()
+	account := faker.NewAccount().WithOwner(user.Username).Get()
+
+	pageSize := 5
+	entries := make([]db.ListEntriesAfterRow, pageSize+1)
+	for i := range entries {
+		entries[i].Entry = faker.NewEntry().WithAccountID(account.ID).Get()
+		entries[i].Entry.ID = int64(i + 1)
+	}
+
+	// the first entry comes from a transfer, so the statement shows who sent it
+	counterparty := faker.NewAccount().Get()
+	entries[0].Entry = faker.NewEntry().
+		WithAccountID(account.ID).
+		WithType(db.EntryTypeTransferCredit).
+		WithTransferID(util.RandomInt(1, 1000)).
+		Get()
+	entries[0].Entry.ID = 1
+	entries[0].CounterpartyAccountID = counterparty.ID
+
+	currency := testCurrency(account.Currency)
+	entriesResponse := make([]entryResponse, len(entries))
+	for i, row := range entries {
+		entriesResponse[i] = newEntryResponse(row.Entry, currency)
+		entriesResponse[i].CounterpartyAccountID = row.CounterpartyAccountID
+	}
+	require.Equal(t, db.EntryTypeTransferCredit, entriesResponse[0].Type)
+	require.NotZero(t, entriesResponse[0].TransferID)
+
+	from := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	to := time.Now().UTC().Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, req *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntriesAfter(gomock.Any(), gomock.Eq(db.ListEntriesAfterParams{
+						AccountID: account.ID,
+						PageSize:  int32(pageSize + 1),
+					})).
+					Times(1).
+					Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[listEntriesResponse](t, recorder.Body, listEntriesResponse{
+					Entries:    entriesResponse[:pageSize],
+					NextCursor: entries[pageSize-1].Entry.ID,
+				})
+			},
+		},
+		{
+			name: "LastPageWithFilters",
+			query: url.Values{
+				"page_size": {fmt.Sprint(pageSize)},
+				"cursor":    {"3"},
+				"from":      {from.Format(time.RFC3339)},
+				"to":        {to.Format(time.RFC3339)},
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntriesAfter(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListEntriesAfterParams) ([]db.ListEntriesAfterRow, error) {
+						require.Equal(t, int64(3), arg.AfterID)
+						require.True(t, arg.FromTime.Valid)
+						require.True(t, from.Equal(arg.FromTime.Time))
+						require.True(t, arg.ToTime.Valid)
+						require.True(t, to.Equal(arg.ToTime.Time))
+						return entries[3:], nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[listEntriesResponse](t, recorder.Body, listEntriesResponse{
+					Entries: entriesResponse[3:],
+				})
+			},
+		},
+		{
+			name: "InvalidRange",
+			query: url.Values{
+				"page_size": {fmt.Sprint(pageSize)},
+				"from":      {to.Format(time.RFC3339)},
+				"to":        {from.Format(time.RFC3339)},
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: url.Values{"page_size": {"1000"}},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "UnauthorizedUser",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntriesAfter(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ListEntriesAfterRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", account.ID, tc.query.Encode())
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
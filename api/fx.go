@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/vadym-98/simple_bank/util"
+	"net/http"
+)
+
+type fxQuoteRequest struct {
+	From   string `form:"from" binding:"required,currency"`
+	To     string `form:"to" binding:"required,currency"`
+	Amount string `form:"amount" binding:"required"`
+}
+
+// getFXQuote shows how much a transfer between accounts in different currencies would credit at the current rate
+func (s *Server) getFXQuote(c *gin.Context) {
+	var req fxQuoteRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	from, ok := s.currency(c, req.From)
+	if !ok {
+		return
+	}
+
+	to, ok := s.currency(c, req.To)
+	if !ok {
+		return
+	}
+
+	amount, ok := parsePositiveAmount(c, req.Amount, from)
+	if !ok {
+		return
+	}
+
+	quote, err := util.QuoteFX(c, s.fx, amount, to)
+	if err != nil {
+		if errors.Is(err, util.ErrUnsupportedCurrencyPair) || errors.Is(err, util.ErrMoneyOverflow) ||
+			errors.Is(err, util.ErrConversionTooSmall) {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
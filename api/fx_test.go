@@ -0,0 +1,73 @@
+package api
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetFXQuoteAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "from=USD&to=EUR&amount=1.01",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[util.FXQuote](t, recorder.Body, util.FXQuote{
+					Rate:            0.5,
+					Amount:          util.NewMoney(101, testCurrency(util.USD)),
+					ConvertedAmount: util.NewMoney(51, testCurrency(util.EUR)),
+				})
+			},
+		},
+		{
+			name:  "UnsupportedCurrencyPair",
+			query: "from=USD&to=CAD&amount=100",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidCurrency",
+			query: "from=USD&to=XYZ&amount=100",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "TooManyDecimals",
+			query: "from=USD&to=EUR&amount=1.001",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidAmount",
+			query: "from=USD&to=EUR&amount=-1",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/fx/quote?"+tc.query, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -2,29 +2,76 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/token"
 	"github.com/vadym-98/simple_bank/util"
+	"github.com/vadym-98/simple_bank/webhook"
 	"io"
+	"net"
 	"os"
 	"testing"
 	"time"
 )
 
+var testCurrencies = []util.Currency{
+	{Code: util.USD, Exponent: 2, Enabled: true},
+	{Code: util.EUR, Exponent: 2, Enabled: true},
+	{Code: util.CAD, Exponent: 2, Enabled: true},
+	{Code: "JPY", Exponent: 0, Enabled: true},
+	{Code: "GBP", Exponent: 2, Enabled: false},
+}
+
+// testCurrency returns one of testCurrencies by its code
+func testCurrency(code string) util.Currency {
+	for _, currency := range testCurrencies {
+		if currency.Code == code {
+			return currency
+		}
+	}
+
+	return util.Currency{}
+}
+
+// testPasswordChanges stands in for the users table behind the revocation store,
+// so the auth checks stay off the mocked store
+type testPasswordChanges map[string]time.Time
+
+func (p testPasswordChanges) GetUserPasswordChangedAt(_ context.Context, username string) (time.Time, error) {
+	return p[username], nil
+}
+
 func newTestServer(t *testing.T, store db.Store) *Server {
 	cfg := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:    util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
 	}
 
-	server, err := NewServer(cfg, store)
+	// the memory store keeps the auth middleware off the mocked store
+	server, err := NewServer(cfg, store, token.NewMemoryRevocationStore(testPasswordChanges{}), mail.NewMemoryMailer(10))
 	require.NoError(t, err)
 
+	server.fx = util.NewStaticFXRateProvider(map[string]float64{util.USD: 1, util.EUR: 0.5, "JPY": 250})
+	server.webhookURLs = webhook.NewURLChecker(publicResolver{}, true)
+	server.currencies = util.NewCurrencyRegistry(func(ctx context.Context) ([]util.Currency, error) {
+		return testCurrencies, nil
+	}, 0)
+
 	return server
 }
 
+// publicResolver resolves every host to a public address without asking a dns server
+type publicResolver struct{}
+
+func (publicResolver) LookupIPAddr(context.Context, string) ([]net.IPAddr, error) {
+	return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode) // for different log output
 	os.Exit(m.Run())
@@ -1,21 +1,28 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
 	"github.com/vadym-98/simple_bank/token"
 	"net/http"
 	"strings"
+	"time"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "authorization_payload"
+	accountKey              = "account"
+	scheduledTransferKey    = "scheduled_transfer"
+	webhookKey              = "webhook"
 )
 
-func authMiddleware(maker token.Maker) gin.HandlerFunc {
+func authMiddleware(maker token.Maker, revocations token.RevocationStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authorizationHeader := c.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
@@ -39,13 +46,153 @@ func authMiddleware(maker token.Maker) gin.HandlerFunc {
 		}
 
 		accessToken := fields[1]
-		payload, err := maker.VerifyToken(accessToken)
+		payload, err := maker.VerifyToken(accessToken, token.TokenTypeAccessToken)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
+		revoked, err := revocations.IsRevoked(c, payload)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if revoked {
+			err := errors.New("token has been revoked")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
 		c.Set(authorizationPayloadKey, payload)
 		c.Next()
 	}
 }
+
+// resourceURI is the :id route param of accounts, scheduled transfers and webhooks
+type resourceURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// ownedResourceMiddleware loads the resource from the :id route param with the get method of the store and
+// aborts unless owner tells it belongs to the authenticated user, handlers find it under key
+func ownedResourceMiddleware[T any](
+	store db.Store,
+	name string,
+	key string,
+	get func(store db.Store, ctx context.Context, id int64) (T, error),
+	owner func(resource T) string,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var uri resourceURI
+		if err := c.ShouldBindUri(&uri); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		resource, err := get(store, c, uri.ID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.AbortWithStatusJSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+
+			c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+		if owner(resource) != authPayload.Username {
+			err := fmt.Errorf("%s doesn't belong to the authenticated user", name)
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		c.Set(key, resource)
+		c.Next()
+	}
+}
+
+// accountMiddleware loads the account from the :id route param and
+// aborts unless it belongs to the authenticated user
+func accountMiddleware(store db.Store) gin.HandlerFunc {
+	return ownedResourceMiddleware(store, "account", accountKey, db.Store.GetAccount, func(account db.Account) string {
+		return account.Owner
+	})
+}
+
+// scheduledTransferMiddleware loads the scheduled transfer from the :id route param and
+// aborts unless it belongs to the authenticated user
+func scheduledTransferMiddleware(store db.Store) gin.HandlerFunc {
+	return ownedResourceMiddleware(store, "scheduled transfer", scheduledTransferKey, db.Store.GetScheduledTransfer,
+		func(scheduled db.ScheduledTransfer) string {
+			return scheduled.Owner
+		})
+}
+
+// webhookMiddleware loads the webhook from the :id route param and aborts unless it belongs to the authenticated user
+func webhookMiddleware(store db.Store) gin.HandlerFunc {
+	return ownedResourceMiddleware(store, "webhook", webhookKey, db.Store.GetWebhook, func(hook db.Webhook) string {
+		return hook.Owner
+	})
+}
+
+// errEmailNotVerified is returned to users who move money before verifying their email while the config requires it
+var errEmailNotVerified = errors.New("email address is not verified")
+
+// requireVerifiedEmail aborts unless the authenticated user verified the email, it does nothing when required is false
+func requireVerifiedEmail(store db.Store, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !required {
+			c.Next()
+			return
+		}
+
+		authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := store.GetUser(c, authPayload.Username)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if !user.IsEmailVerified {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errEmailNotVerified))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireRole aborts unless the role carried by the access token is one of roles
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		for _, role := range roles {
+			if authPayload.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		err := fmt.Errorf("role %q is not allowed to access this resource", authPayload.Role)
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+	}
+}
+
+// timeoutMiddleware puts a deadline on the request context, so the store calls made by handlers are cancelled once it passes
+func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
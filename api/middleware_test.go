@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -17,10 +19,12 @@ func addAuthorization(
 	maker token.Maker,
 	authorizationType string,
 	username string,
+	role string,
 	duration time.Duration,
 ) {
-	tkn, err := maker.CreateToken(username, duration)
+	tkn, payload, err := maker.CreateToken(username, role, token.TokenTypeAccessToken, duration)
 	require.NoError(t, err)
+	require.NotEmpty(t, payload)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, tkn)
 	rq.Header.Set(authorizationHeaderKey, authorizationHeader)
@@ -35,7 +39,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "OK",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, "user", util.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -52,7 +56,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "UnsupportedAuthorization",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, "unsupported", "user", time.Minute)
+				addAuthorization(t, req, tokenMaker, "unsupported", "user", util.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -61,7 +65,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "InvalidAuthorizationFormat",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, "", "user", time.Minute)
+				addAuthorization(t, req, tokenMaker, "", "user", util.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -70,7 +74,18 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, "user", -time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, "user", util.DepositorRole, -time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				tkn, _, err := tokenMaker.CreateToken("user", util.DepositorRole, token.TokenTypeRefreshToken, time.Minute)
+				require.NoError(t, err)
+				req.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tkn))
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -83,7 +98,7 @@ func TestAuthMiddleware(t *testing.T) {
 			srv := newTestServer(t, nil)
 
 			authPath := "/auth"
-			srv.router.GET(authPath, authMiddleware(srv.tokenMaker), func(c *gin.Context) {
+			srv.router.GET(authPath, authMiddleware(srv.tokenMaker, srv.revocations), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{})
 			})
 
@@ -97,3 +112,96 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddlewareRevokedToken(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	authPath := "/auth"
+	srv.router.GET(authPath, authMiddleware(srv.tokenMaker, srv.revocations), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	tkn, payload, err := srv.tokenMaker.CreateToken("user", util.DepositorRole, token.TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, srv.revocations.Revoke(context.Background(), payload))
+
+	recorder := httptest.NewRecorder()
+	rq, err := http.NewRequest(http.MethodGet, authPath, nil)
+	require.NoError(t, err)
+
+	rq.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tkn))
+	srv.router.ServeHTTP(recorder, rq)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	testCases := []struct {
+		name         string
+		timeout      time.Duration
+		checkContext func(t *testing.T, c *gin.Context)
+	}{
+		{
+			name:    "Deadline",
+			timeout: time.Minute,
+			checkContext: func(t *testing.T, c *gin.Context) {
+				deadline, ok := c.Deadline()
+				require.True(t, ok)
+				require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+			},
+		},
+		{
+			name:    "Disabled",
+			timeout: 0,
+			checkContext: func(t *testing.T, c *gin.Context) {
+				_, ok := c.Deadline()
+				require.False(t, ok)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.ContextWithFallback = true
+
+			path := "/timeout"
+			router.GET(path, timeoutMiddleware(tc.timeout), func(c *gin.Context) {
+				tc.checkContext(t, c)
+				c.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			rq, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+
+			router.ServeHTTP(recorder, rq)
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
+
+func TestAuthMiddlewarePasswordChanged(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	oldToken, _, err := srv.tokenMaker.CreateToken("user", util.DepositorRole, token.TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+	// the revocation store reads the change from the users table
+	revocations := token.NewMemoryRevocationStore(testPasswordChanges{"user": time.Now()})
+	newToken, _, err := srv.tokenMaker.CreateToken("user", util.DepositorRole, token.TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	authPath := "/auth"
+	srv.router.GET(authPath, authMiddleware(srv.tokenMaker, revocations), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	for tkn, code := range map[string]int{oldToken: http.StatusUnauthorized, newToken: http.StatusOK} {
+		recorder := httptest.NewRecorder()
+		rq, err := http.NewRequest(http.MethodGet, authPath, nil)
+		require.NoError(t, err)
+
+		rq.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tkn))
+		srv.router.ServeHTTP(recorder, rq)
+		require.Equal(t, code, recorder.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/vadym-98/simple_bank/util"
+	"net/http"
+)
+
+// currency looks up a currency already used by an account or accepted by the validator
+func (s *Server) currency(c *gin.Context, code string) (util.Currency, bool) {
+	currency, ok := s.currencies.Get(c, code)
+	if !ok {
+		err := fmt.Errorf("unknown currency %s", code)
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return currency, false
+	}
+
+	return currency, true
+}
+
+// parsePositiveAmount reads a decimal amount like "12.34" that must be greater than zero
+func parsePositiveAmount(c *gin.Context, value string, currency util.Currency) (util.Money, bool) {
+	amount, ok := parseAmount(c, value, currency)
+	if ok && amount.Amount <= 0 {
+		err := errors.New("amount must be positive")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return amount, false
+	}
+
+	return amount, ok
+}
+
+func parseAmount(c *gin.Context, value string, currency util.Currency) (util.Money, bool) {
+	amount, err := util.ParseMoney(value, currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return amount, false
+	}
+
+	return amount, true
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
+	"net/http"
+	"time"
+)
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// changePassword sets a new password once the old one is confirmed,
+// all tokens and sessions of the user including the one used for the request stop working
+func (s *Server) changePassword(c *gin.Context) {
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := s.store.GetUser(c, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := util.CheckPassword(req.OldPassword, user.HashedPassword); err != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	hashedPwd, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPwd,
+		ChangedAt:      time.Now(),
+	}
+
+	user, err = s.store.ChangePasswordTx(c, arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type requestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// requestPasswordReset mails a reset code to the user with the email
+// The email is sent in the background and the response is the same whether a user has the email or not,
+// so neither the status nor the response time tell which emails belong to a user
+func (s *Server) requestPasswordReset(c *gin.Context) {
+	var req requestPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, err := s.store.CreatePasswordResetTx(c, db.CreatePasswordResetTxParams{
+		Email: req.Email,
+		AfterCommit: func(user db.User, reset db.PasswordReset) error {
+			s.sendInBackground(mail.PasswordResetMessage(s.config.PasswordResetURL, user, reset))
+			return nil
+		},
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type confirmPasswordResetRequest struct {
+	ResetID     int64  `json:"reset_id" binding:"required,min=1"`
+	SecretCode  string `json:"secret_code" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// confirmPasswordReset sets a new password with the code from the reset email, it needs no access token
+func (s *Server) confirmPasswordReset(c *gin.Context) {
+	var req confirmPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPwd, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.ResetPasswordTxParams{
+		ResetID:        req.ResetID,
+		SecretCode:     req.SecretCode,
+		HashedPassword: hashedPwd,
+		ChangedAt:      time.Now(),
+	}
+
+	user, err := s.store.ResetPasswordTx(c, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrPasswordResetInvalid) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
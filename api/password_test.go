@@ -0,0 +1,446 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vadym-98/simple_bank/db/mock"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
+	"github.com/vadym-98/simple_bank/util/faker"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type eqChangePasswordTxParamsMatcher struct {
+	username string
+	password string
+}
+
+func (e eqChangePasswordTxParamsMatcher) Matches(x any) bool {
+	arg, ok := x.(db.ChangePasswordTxParams)
+	if !ok {
+		return false
+	}
+
+	return arg.Username == e.username &&
+		util.CheckPassword(e.password, arg.HashedPassword) == nil &&
+		!arg.ChangedAt.IsZero()
+}
+
+func (e eqChangePasswordTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches username %v and password %v", e.username, e.password)
+}
+
+func EqChangePasswordTxParams(username string, pwd string) gomock.Matcher {
+	return eqChangePasswordTxParamsMatcher{username: username, password: pwd}
+}
+
+type eqResetPasswordTxParamsMatcher struct {
+	resetID    int64
+	secretCode string
+	password   string
+}
+
+func (e eqResetPasswordTxParamsMatcher) Matches(x any) bool {
+	arg, ok := x.(db.ResetPasswordTxParams)
+	if !ok {
+		return false
+	}
+
+	return arg.ResetID == e.resetID &&
+		arg.SecretCode == e.secretCode &&
+		util.CheckPassword(e.password, arg.HashedPassword) == nil &&
+		!arg.ChangedAt.IsZero()
+}
+
+func (e eqResetPasswordTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches reset %v, code %v and password %v", e.resetID, e.secretCode, e.password)
+}
+
+func EqResetPasswordTxParams(resetID int64, secretCode string, pwd string) gomock.Matcher {
+	return eqResetPasswordTxParamsMatcher{resetID: resetID, secretCode: secretCode, password: pwd}
+}
+
+// failingMailer fails every send like an unreachable smtp server
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mail.Message) error {
+	return errors.New("smtp server is down")
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	const oldPwd = "mysecret"
+	const newPwd = "n3wsecret"
+	user := faker.NewUser().Get()
+	hashedPwd, err := util.HashPassword(oldPwd)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPwd
+
+	changed := user
+	changed.PasswordChangedAt = time.Now().UTC().Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		body          changePasswordRequest
+		setupAuth     func(t *testing.T, req *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: changePasswordRequest{OldPassword: oldPwd, NewPassword: newPwd},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), EqChangePasswordTxParams(user.Username, newPwd)).
+					Times(1).
+					Return(changed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[userResponse](t, recorder.Body, newUserResponse(changed))
+			},
+		},
+		{
+			name: "WrongOldPassword",
+			body: changePasswordRequest{OldPassword: "wrong-password", NewPassword: newPwd},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: changePasswordRequest{OldPassword: oldPwd, NewPassword: newPwd},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TooShortNewPassword",
+			body: changePasswordRequest{OldPassword: oldPwd, NewPassword: "123"},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: changePasswordRequest{OldPassword: oldPwd, NewPassword: newPwd},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: changePasswordRequest{OldPassword: oldPwd, NewPassword: newPwd},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPut, "/users/me/password", createBody(t, tc.body))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRequestPasswordResetAPI(t *testing.T) {
+	user := faker.NewUser().Get()
+	reset := db.PasswordReset{
+		ID:         util.RandomInt(1, 1000),
+		Username:   user.Username,
+		SecretCode: util.RandomString(32),
+		ExpiredAt:  time.Now().Add(15 * time.Minute),
+	}
+
+	testCases := []struct {
+		name          string
+		body          requestPasswordResetRequest
+		buildStubs    func(store *mockdb.MockStore)
+		mailer        mail.Mailer
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+		checkMailer   func(t *testing.T, mailer *mail.MemoryMailer)
+	}{
+		{
+			name: "OK",
+			body: requestPasswordResetRequest{Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreatePasswordResetTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetTxParams) (db.CreatePasswordResetTxResult, error) {
+						require.Equal(t, user.Email, arg.Email)
+						result := db.CreatePasswordResetTxResult{User: user, PasswordReset: reset}
+						return result, arg.AfterCommit(result.User, result.PasswordReset)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+			checkMailer: func(t *testing.T, mailer *mail.MemoryMailer) {
+				// the email goes out after the response
+				require.Eventually(t, func() bool { return len(mailer.Sent()) == 1 }, time.Second, 10*time.Millisecond)
+
+				sent := mailer.Sent()
+				require.Equal(t, []string{user.Email}, sent[0].To)
+				require.Contains(t, sent[0].Body, reset.SecretCode)
+			},
+		},
+		{
+			name: "SendEmailFailed",
+			body: requestPasswordResetRequest{Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreatePasswordResetTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetTxParams) (db.CreatePasswordResetTxResult, error) {
+						result := db.CreatePasswordResetTxResult{User: user, PasswordReset: reset}
+						return result, arg.AfterCommit(result.User, result.PasswordReset)
+					})
+			},
+			mailer: failingMailer{},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: requestPasswordResetRequest{Email: util.RandomEmail()},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreatePasswordResetTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreatePasswordResetTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+			checkMailer: func(t *testing.T, mailer *mail.MemoryMailer) {
+				require.Empty(t, mailer.Sent())
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: requestPasswordResetRequest{Email: "asdas"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreatePasswordResetTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: requestPasswordResetRequest{Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreatePasswordResetTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreatePasswordResetTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			if tc.mailer != nil {
+				server.mailer = tc.mailer
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password_reset", createBody(t, tc.body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+			if tc.checkMailer != nil {
+				tc.checkMailer(t, server.mailer.(*mail.MemoryMailer))
+			}
+			server.pendingMails.Wait()
+		})
+	}
+}
+
+func TestConfirmPasswordResetAPI(t *testing.T) {
+	const newPwd = "n3wsecret"
+	user := faker.NewUser().Get()
+	user.PasswordChangedAt = time.Now().UTC().Truncate(time.Second)
+	req := confirmPasswordResetRequest{
+		ResetID:     util.RandomInt(1, 1000),
+		SecretCode:  util.RandomString(32),
+		NewPassword: newPwd,
+	}
+
+	testCases := []struct {
+		name          string
+		body          confirmPasswordResetRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), EqResetPasswordTxParams(req.ResetID, req.SecretCode, newPwd)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[userResponse](t, recorder.Body, newUserResponse(user))
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrPasswordResetInvalid)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "TooShortPassword",
+			body: confirmPasswordResetRequest{
+				ResetID:     req.ResetID,
+				SecretCode:  req.SecretCode,
+				NewPassword: "123",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password_reset/confirm", createBody(t, tc.body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
+	"net/http"
+	"time"
+)
+
+var errScheduledTransferNotActive = errors.New("scheduled transfer is completed or cancelled")
+
+type scheduledTransferResponse struct {
+	ID            int64                      `json:"id"`
+	FromAccountID int64                      `json:"from_account_id"`
+	ToAccountID   int64                      `json:"to_account_id"`
+	Amount        util.Money                 `json:"amount"`
+	Recurrence    db.Recurrence              `json:"recurrence"`
+	StartAt       time.Time                  `json:"start_at"`
+	NextRunAt     time.Time                  `json:"next_run_at"`
+	LastRunAt     *time.Time                 `json:"last_run_at,omitempty"`
+	Status        db.ScheduledTransferStatus `json:"status"`
+	CreatedAt     time.Time                  `json:"created_at"`
+}
+
+func newScheduledTransferResponse(scheduled db.ScheduledTransfer, currency util.Currency) scheduledTransferResponse {
+	rsp := scheduledTransferResponse{
+		ID:            scheduled.ID,
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        util.NewMoney(scheduled.Amount, currency),
+		Recurrence:    scheduled.Recurrence,
+		StartAt:       scheduled.StartAt,
+		NextRunAt:     scheduled.NextRunAt,
+		Status:        scheduled.Status,
+		CreatedAt:     scheduled.CreatedAt,
+	}
+	if scheduled.LastRunAt.Valid {
+		rsp.LastRunAt = &scheduled.LastRunAt.Time
+	}
+	return rsp
+}
+
+// scheduledTransferRequest is the schedule of a transfer between two accounts of the same currency
+type scheduledTransferRequest struct {
+	FromAccountID int64     `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64     `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        string    `json:"amount" binding:"required"`
+	Currency      string    `json:"currency" binding:"required,currency"`
+	Recurrence    string    `json:"recurrence" binding:"required,oneof=once daily weekly monthly"`
+	StartAt       time.Time `json:"start_at" binding:"required"`
+}
+
+func (s *Server) createScheduledTransfer(c *gin.Context) {
+	var req scheduledTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !validStartAt(c, req.StartAt) {
+		return
+	}
+
+	currency, ok := s.currency(c, req.Currency)
+	if !ok {
+		return
+	}
+
+	amount, ok := parsePositiveAmount(c, req.Amount, currency)
+	if !ok {
+		return
+	}
+
+	fromAccount, valid := s.validAccount(c, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Username != fromAccount.Owner {
+		err := errors.New("from account doesn't belong to authenticated user")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// the amount is fixed when the transfer is scheduled, so there is no exchange rate to quote at run time
+	toAccount, valid := s.validAccount(c, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if toAccount.Kind != db.AccountKindCustomer {
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(db.ErrSystemAccount))
+		return
+	}
+
+	scheduled, err := s.store.CreateScheduledTransfer(c, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        amount.Amount,
+		Recurrence:    db.Recurrence(req.Recurrence),
+		StartAt:       req.StartAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, newScheduledTransferResponse(scheduled, currency))
+}
+
+func validStartAt(c *gin.Context, startAt time.Time) bool {
+	if !startAt.After(time.Now()) {
+		err := errors.New("start_at must be in the future")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return false
+	}
+
+	return true
+}
+
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (s *Server) listScheduledTransfers(c *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	rows, err := s.store.ListScheduledTransfers(c, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]scheduledTransferResponse, len(rows))
+	for i, row := range rows {
+		currency, ok := s.currency(c, row.Currency)
+		if !ok {
+			return
+		}
+		rsp[i] = newScheduledTransferResponse(row.ScheduledTransfer, currency)
+	}
+
+	c.JSON(http.StatusOK, rsp)
+}
+
+func (s *Server) getScheduledTransfer(c *gin.Context) {
+	scheduled := c.MustGet(scheduledTransferKey).(db.ScheduledTransfer)
+	s.writeScheduledTransfer(c, scheduled)
+}
+
+type updateScheduledTransferRequest struct {
+	Amount     string    `json:"amount" binding:"required"`
+	Recurrence string    `json:"recurrence" binding:"required,oneof=once daily weekly monthly"`
+	StartAt    time.Time `json:"start_at" binding:"required"`
+}
+
+// updateScheduledTransfer replaces the amount and the schedule, the next run is start_at again
+func (s *Server) updateScheduledTransfer(c *gin.Context) {
+	var req updateScheduledTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !validStartAt(c, req.StartAt) {
+		return
+	}
+
+	scheduled := c.MustGet(scheduledTransferKey).(db.ScheduledTransfer)
+	if scheduled.Status != db.ScheduledTransferStatusActive {
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(errScheduledTransferNotActive))
+		return
+	}
+
+	currency, ok := s.accountCurrency(c, scheduled.FromAccountID)
+	if !ok {
+		return
+	}
+
+	amount, ok := parsePositiveAmount(c, req.Amount, currency)
+	if !ok {
+		return
+	}
+
+	scheduled, err := s.store.UpdateScheduledTransfer(c, db.UpdateScheduledTransferParams{
+		ID:         scheduled.ID,
+		Amount:     amount.Amount,
+		Recurrence: db.Recurrence(req.Recurrence),
+		StartAt:    req.StartAt,
+	})
+	if err != nil {
+		scheduledTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newScheduledTransferResponse(scheduled, currency))
+}
+
+// cancelScheduledTransfer stops future runs, the runs so far stay queryable
+func (s *Server) cancelScheduledTransfer(c *gin.Context) {
+	scheduled := c.MustGet(scheduledTransferKey).(db.ScheduledTransfer)
+	if scheduled.Status != db.ScheduledTransferStatusActive {
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(errScheduledTransferNotActive))
+		return
+	}
+
+	scheduled, err := s.store.CancelScheduledTransfer(c, scheduled.ID)
+	if err != nil {
+		scheduledTransferError(c, err)
+		return
+	}
+
+	s.writeScheduledTransfer(c, scheduled)
+}
+
+// scheduledTransferError maps the update of a schedule that a worker completed in the meantime to 422
+func scheduledTransferError(c *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(errScheduledTransferNotActive))
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, errorResponse(err))
+}
+
+type scheduledTransferRunResponse struct {
+	ID           int64                 `json:"id"`
+	TransferID   int64                 `json:"transfer_id,omitempty"`
+	Status       db.ScheduledRunStatus `json:"status"`
+	Error        string                `json:"error,omitempty"`
+	ScheduledFor time.Time             `json:"scheduled_for"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+func newScheduledTransferRunResponse(run db.ScheduledTransferRun) scheduledTransferRunResponse {
+	return scheduledTransferRunResponse{
+		ID:           run.ID,
+		TransferID:   run.TransferID.Int64,
+		Status:       run.Status,
+		Error:        run.Error,
+		ScheduledFor: run.ScheduledFor,
+		CreatedAt:    run.CreatedAt,
+	}
+}
+
+type listScheduledTransferRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listScheduledTransferRuns returns the outcome of every run, the latest first
+func (s *Server) listScheduledTransferRuns(c *gin.Context) {
+	var req listScheduledTransferRunsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled := c.MustGet(scheduledTransferKey).(db.ScheduledTransfer)
+	runs, err := s.store.ListScheduledTransferRuns(c, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]scheduledTransferRunResponse, len(runs))
+	for i, run := range runs {
+		rsp[i] = newScheduledTransferRunResponse(run)
+	}
+
+	c.JSON(http.StatusOK, rsp)
+}
+
+func (s *Server) writeScheduledTransfer(c *gin.Context, scheduled db.ScheduledTransfer) {
+	currency, ok := s.accountCurrency(c, scheduled.FromAccountID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, newScheduledTransferResponse(scheduled, currency))
+}
+
+// accountCurrency returns the currency of the account, amounts of scheduled transfers are in the source currency
+func (s *Server) accountCurrency(c *gin.Context, accountID int64) (util.Currency, bool) {
+	account, err := s.store.GetAccount(c, accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return util.Currency{}, false
+	}
+
+	return s.currency(c, account.Currency)
+}
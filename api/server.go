@@ -1,38 +1,67 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
 	"github.com/vadym-98/simple_bank/token"
 	"github.com/vadym-98/simple_bank/util"
+	"github.com/vadym-98/simple_bank/webhook"
 	"log"
+	"net"
+	"net/http"
+	"sync"
 )
 
 // Server serves HTTP requests for banking service
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config      util.Config
+	store       db.Store
+	tokenMaker  token.Maker
+	revocations token.RevocationStore
+	mailer      mail.Mailer
+	fx          util.FXRateProvider
+	currencies  *util.CurrencyRegistry
+	webhookURLs *webhook.URLChecker
+	router      *gin.Engine
+	httpServer  *http.Server
+	// baseCtx is the parent of every request context and of the emails sent in the background
+	baseCtx context.Context
+	// cancelRequests aborts the handlers and emails still running once the drain timeout is over
+	cancelRequests context.CancelFunc
+	pendingMails   sync.WaitGroup
 }
 
-func NewServer(cfg util.Config, store db.Store) (*Server, error) {
+// NewServer creates the HTTP server, revocations and mailer are shared with the other servers of the process
+func NewServer(cfg util.Config, store db.Store, revocations token.RevocationStore, mailer mail.Mailer) (*Server, error) {
 	tokenMaker, err := token.NewPasetoMaker(cfg.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	fx, err := util.NewFXRateProvider(cfg.FXRatesFile)
+	if err != nil {
+		return nil, err
+	}
+
 	server := &Server{
-		config:     cfg,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:      cfg,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		revocations: revocations,
+		mailer:      mailer,
+		fx:          fx,
+		currencies:  util.NewCurrencyRegistry(db.LoadCurrencies(store), cfg.CurrencyCacheTTL),
+		webhookURLs: webhook.NewURLChecker(net.DefaultResolver, cfg.Environment != util.DevelopmentEnvironment),
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		err := v.RegisterValidation("currency", validCurrency)
+		err := v.RegisterValidation("currency", server.validCurrency)
 		if err != nil {
 			log.Fatalln("failed to register validator")
 		}
@@ -40,30 +69,140 @@ func NewServer(cfg util.Config, store db.Store) (*Server, error) {
 
 	server.setupRouter()
 
+	baseCtx, cancel := context.WithCancel(context.Background())
+	server.baseCtx = baseCtx
+	server.cancelRequests = cancel
+	server.httpServer = &http.Server{
+		Handler:     server.router,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+
 	return server, nil
 }
 
 func (s *Server) setupRouter() {
 	router := gin.Default()
+	// makes gin.Context report the request cancellation, so it reaches the store calls
+	router.ContextWithFallback = true
+	router.Use(timeoutMiddleware(s.config.RequestTimeout))
 
 	router.POST("/users", s.createUser)
 	router.POST("/users/login", s.loginUser)
+	router.GET("/users/verify_email", s.verifyEmail)
+	router.POST("/users/password_reset", s.requestPasswordReset)
+	router.POST("/users/password_reset/confirm", s.confirmPasswordReset)
+	router.POST("/tokens/renew_access", s.renewAccessToken)
+	router.GET("/fx/quote", s.getFXQuote)
 
-	authRoutes := router.Group("/", authMiddleware(s.tokenMaker))
+	authRoutes := router.Group("/", authMiddleware(s.tokenMaker, s.revocations))
+
+	authRoutes.POST("/users/logout", s.logoutUser)
+	authRoutes.PUT("/users/me/password", s.changePassword)
+	authRoutes.POST("/users/verify_email/resend", s.resendVerifyEmail)
+
+	authRoutes.GET("/sessions", s.listSessions)
+	authRoutes.DELETE("/sessions/:id", s.blockSession)
 
 	authRoutes.POST("/accounts", s.createAccount)
-	authRoutes.GET("/accounts/:id", s.getAccount)
 	authRoutes.GET("/accounts", s.listAccount)
-	authRoutes.PUT("/accounts/:id", s.updateAccount)
-	authRoutes.DELETE("/accounts/:id", s.deleteAccount)
 
-	authRoutes.POST("/transfers", s.createTransfer)
+	accountRoutes := authRoutes.Group("/accounts/:id", accountMiddleware(s.store))
+
+	accountRoutes.GET("", s.getAccount)
+	accountRoutes.DELETE("", s.closeAccount)
+	accountRoutes.POST("/deposits", s.createDeposit)
+	accountRoutes.POST("/withdrawals", s.createWithdrawal)
+	accountRoutes.GET("/entries", s.listEntries)
+	accountRoutes.GET("/transfers", s.listTransfers)
+
+	adminRoutes := authRoutes.Group("/", requireRole(util.AdminRole))
+
+	adminRoutes.GET("/admin/accounts", s.listAllAccounts)
+	adminRoutes.GET("/admin/stats/transactions", s.getTxStats)
+	adminRoutes.PUT("/accounts/:id", s.updateAccount)
+	adminRoutes.PUT("/accounts/:id/status", s.setAccountStatus)
+	adminRoutes.POST("/accounts/:id/adjustments", s.adjustBalance)
+
+	verifiedEmail := requireVerifiedEmail(s.store, s.config.RequireVerifiedEmail)
+
+	authRoutes.POST("/transfers", verifiedEmail, s.createTransfer)
+	authRoutes.GET("/transfers/:id", s.getTransfer)
+
+	authRoutes.POST("/scheduled-transfers", verifiedEmail, s.createScheduledTransfer)
+	authRoutes.GET("/scheduled-transfers", s.listScheduledTransfers)
+
+	scheduledRoutes := authRoutes.Group("/scheduled-transfers/:id", scheduledTransferMiddleware(s.store))
+
+	scheduledRoutes.GET("", s.getScheduledTransfer)
+	scheduledRoutes.PUT("", s.updateScheduledTransfer)
+	scheduledRoutes.DELETE("", s.cancelScheduledTransfer)
+	scheduledRoutes.GET("/runs", s.listScheduledTransferRuns)
+
+	authRoutes.POST("/webhooks", s.createWebhook)
+	authRoutes.GET("/webhooks", s.listWebhooks)
+
+	webhookRoutes := authRoutes.Group("/webhooks/:id", webhookMiddleware(s.store))
+
+	webhookRoutes.GET("", s.getWebhook)
+	webhookRoutes.DELETE("", s.deleteWebhook)
+	webhookRoutes.GET("/deliveries", s.listWebhookDeliveries)
 
 	s.router = router
 }
 
+// Start serves requests on address until Shutdown is called
 func (s *Server) Start(address string) error {
-	return s.router.Run(address)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot create listener: %w", err)
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve serves requests on listener until Shutdown is called
+func (s *Server) Serve(listener net.Listener) error {
+	if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// sendInBackground sends msg without holding up the response, failures are only logged
+func (s *Server) sendInBackground(msg mail.Message) {
+	s.pendingMails.Add(1)
+	go func() {
+		defer s.pendingMails.Done()
+
+		if err := s.mailer.Send(s.baseCtx, msg); err != nil {
+			log.Println("failed to send email:", err)
+		}
+	}()
+}
+
+// Shutdown stops accepting requests and waits for the running ones until ctx is done,
+// then cancels their context and closes the remaining connections
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+
+	// emails still being sent get the rest of the drain timeout
+	sent := make(chan struct{})
+	go func() {
+		s.pendingMails.Wait()
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-ctx.Done():
+	}
+
+	s.cancelRequests()
+	if err != nil {
+		s.httpServer.Close()
+	}
+
+	return err
 }
 
 func errorResponse(err error) gin.H {
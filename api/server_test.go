@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// serveSlowRoute serves the test server with a route that blocks until handle returns
+func serveSlowRoute(t *testing.T, handle func(c *gin.Context)) (*Server, chan error) {
+	server := newTestServer(t, nil)
+
+	started := make(chan struct{})
+	server.router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		handle(c)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	go func() {
+		rsp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err == nil {
+			rsp.Body.Close()
+		}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("request didn't reach the handler")
+	}
+
+	return server, served
+}
+
+func TestServerShutdownDrainsRequests(t *testing.T) {
+	release := make(chan struct{})
+	server, served := serveSlowRoute(t, func(c *gin.Context) {
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown returned before the running request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-shutdown)
+	require.NoError(t, <-served)
+}
+
+func TestServerShutdownCancelsRequestsAfterTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	server, served := serveSlowRoute(t, func(c *gin.Context) {
+		<-c.Done()
+		close(cancelled)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+	require.NoError(t, <-served)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("running request wasn't cancelled")
+	}
+}
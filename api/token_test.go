@@ -0,0 +1,191 @@
+package api
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vadym-98/simple_bank/db/mock"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util/faker"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user := faker.NewUser().Get()
+
+	testCases := []struct {
+		name          string
+		buildSession  func(refreshToken string, payload *token.Payload) db.Session
+		refreshToken  func(t *testing.T, maker token.Maker) (string, *token.Payload)
+		buildStubs    func(store *mockdb.MockStore, payload *token.Payload, session db.Session)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return newTestSession(refreshToken, payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidToken",
+			refreshToken: func(t *testing.T, maker token.Maker) (string, *token.Payload) {
+				return "invalid", &token.Payload{}
+			},
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return db.Session{}
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			refreshToken: func(t *testing.T, maker token.Maker) (string, *token.Payload) {
+				tkn, payload, err := maker.CreateToken(user.Username, user.Role, token.TokenTypeAccessToken, time.Hour)
+				require.NoError(t, err)
+				return tkn, payload
+			},
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return db.Session{}
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return db.Session{}
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return db.Session{}
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := newTestSession(refreshToken, payload)
+				session.IsBlocked = true
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectUser",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := newTestSession(refreshToken, payload)
+				session.Username = "other_user"
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MismatchedToken",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return newTestSession("other_token", payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := newTestSession(refreshToken, payload)
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			var refreshToken string
+			var payload *token.Payload
+			if tc.refreshToken != nil {
+				refreshToken, payload = tc.refreshToken(t, server.tokenMaker)
+			} else {
+				var err error
+				refreshToken, payload, err = server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefreshToken, time.Hour)
+				require.NoError(t, err)
+			}
+
+			tc.buildStubs(store, payload, tc.buildSession(refreshToken, payload))
+			recorder := httptest.NewRecorder()
+
+			body := renewAccessTokenRequest{RefreshToken: refreshToken}
+			req, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", createBody(t, body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func newTestSession(refreshToken string, payload *token.Payload) db.Session {
+	return db.Session{
+		ID:           payload.ID,
+		Username:     payload.Username,
+		RefreshToken: refreshToken,
+		ExpiresAt:    payload.ExpiredAt,
+	}
+}
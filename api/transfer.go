@@ -1,22 +1,69 @@
 package api
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
 	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
 	"net/http"
+	"time"
 )
 
+const idempotencyKeyHeader = "Idempotency-Key"
+
 type transferRequest struct {
 	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Amount        string `json:"amount" binding:"required"`
 	Currency      string `json:"currency" binding:"required,currency"`
 }
 
+type transferResponse struct {
+	ID            int64      `json:"id"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        util.Money `json:"amount"`
+	ToAmount      util.Money `json:"to_amount"`
+	ExchangeRate  float64    `json:"exchange_rate"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func newTransferResponse(transfer db.Transfer, from util.Currency, to util.Currency) transferResponse {
+	return transferResponse{
+		ID:            transfer.ID,
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        util.NewMoney(transfer.Amount, from),
+		ToAmount:      util.NewMoney(transfer.ToAmount, to),
+		ExchangeRate:  transfer.ExchangeRate,
+		CreatedAt:     transfer.CreatedAt,
+	}
+}
+
+type transferTxResponse struct {
+	Transfer    transferResponse `json:"transfer"`
+	FromAccount accountResponse  `json:"from_account"`
+	ToAccount   accountResponse  `json:"to_account"`
+	FromEntry   entryResponse    `json:"from_entry"`
+	ToEntry     entryResponse    `json:"to_entry"`
+}
+
+func newTransferTxResponse(result db.TransferTxResult, from util.Currency, to util.Currency) transferTxResponse {
+	return transferTxResponse{
+		Transfer:    newTransferResponse(result.Transfer, from, to),
+		FromAccount: newAccountResponse(result.FromAccount, from),
+		ToAccount:   newAccountResponse(result.ToAccount, to),
+		FromEntry:   newEntryResponse(result.FromEntry, from),
+		ToEntry:     newEntryResponse(result.ToEntry, to),
+	}
+}
+
 func (s *Server) createTransfer(c *gin.Context) {
 	var req transferRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -24,6 +71,16 @@ func (s *Server) createTransfer(c *gin.Context) {
 		return
 	}
 
+	from, ok := s.currency(c, req.Currency)
+	if !ok {
+		return
+	}
+
+	amount, ok := parsePositiveAmount(c, req.Amount, from)
+	if !ok {
+		return
+	}
+
 	fromAccount, valid := s.validAccount(c, req.FromAccountID, req.Currency)
 	if !valid {
 		return
@@ -36,27 +93,124 @@ func (s *Server) createTransfer(c *gin.Context) {
 		return
 	}
 
-	_, valid = s.validAccount(c, req.ToAccountID, req.Currency)
+	toAccount, valid := s.findAccount(c, req.ToAccountID)
 	if !valid {
 		return
 	}
 
+	to := from
 	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+		Amount:        amount.Amount,
 	}
 
-	result, err := s.store.TransferTx(c, arg)
+	if toAccount.Currency != fromAccount.Currency {
+		if to, ok = s.currency(c, toAccount.Currency); !ok {
+			return
+		}
+
+		quote, err := util.QuoteFX(c, s.fx, amount, to)
+		if err != nil {
+			if errors.Is(err, util.ErrUnsupportedCurrencyPair) || errors.Is(err, util.ErrMoneyOverflow) ||
+				errors.Is(err, util.ErrConversionTooSmall) {
+				c.JSON(http.StatusBadRequest, errorResponse(err))
+				return
+			}
+
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		arg.ToAmount = quote.ConvertedAmount.Amount
+		arg.ExchangeRate = quote.Rate
+	}
+
+	result, err := s.transfer(c, authPayload.Username, req.Currency, arg)
 	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrAccountNotActive) ||
+			errors.Is(err, db.ErrSystemAccount) || errors.Is(err, db.ErrBalanceOverflow) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrTxRetriesExhausted) {
+			c.JSON(http.StatusServiceUnavailable, errorResponse(err))
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, newTransferTxResponse(result, from, to))
+}
+
+// idempotentTransfer is what a replay of a transfer has to repeat, the amount is in minor units
+// so that the ways of writing it, like "12.3" and "12.30", are the same request
+type idempotentTransfer struct {
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Currency      string `json:"currency"`
+	Amount        int64  `json:"amount"`
+}
+
+// transfer runs the transfer once per Idempotency-Key when the client provides one
+func (s *Server) transfer(c *gin.Context, username string, currency string, arg db.TransferTxParams) (db.TransferTxResult, error) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if len(key) == 0 {
+		return s.store.TransferTx(c, arg)
+	}
+
+	requestHash, err := hashRequest(idempotentTransfer{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Currency:      currency,
+		Amount:        arg.Amount,
+	})
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	return s.store.IdempotentTransferTx(c, db.IdempotentTransferTxParams{
+		TransferTxParams: arg,
+		Username:         username,
+		Key:              key,
+		RequestHash:      requestHash,
+	})
+}
+
+func hashRequest(req interface{}) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
 }
 
 func (s *Server) validAccount(c *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, valid := s.findAccount(c, accountID)
+	if !valid {
+		return account, false
+	}
+
+	if account.Currency != currency {
+		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return account, false
+	}
+
+	return account, true
+}
+
+func (s *Server) findAccount(c *gin.Context, accountID int64) (db.Account, bool) {
 	account, err := s.store.GetAccount(c, accountID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -68,11 +222,187 @@ func (s *Server) validAccount(c *gin.Context, accountID int64, currency string)
 		return account, false
 	}
 
-	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+	return account, true
+}
+
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getTransfer returns the transfer if the authenticated user owns either of its accounts
+func (s *Server) getTransfer(c *gin.Context) {
+	var req getTransferRequest
+
+	if err := c.ShouldBindUri(&req); err != nil {
 		c.JSON(http.StatusBadRequest, errorResponse(err))
-		return account, false
+		return
 	}
 
-	return account, true
+	transfer, err := s.store.GetTransfer(c, req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	fromAccount, err := s.store.GetAccount(c, transfer.FromAccountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	toAccount, err := s.store.GetAccount(c, transfer.ToAccountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username && toAccount.Owner != authPayload.Username {
+		err = errors.New("transfer doesn't belong to the authenticated user")
+		c.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	from, ok := s.currency(c, fromAccount.Currency)
+	if !ok {
+		return
+	}
+
+	to, ok := s.currency(c, toAccount.Currency)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, newTransferResponse(transfer, from, to))
+}
+
+const (
+	directionIncoming = "incoming"
+	directionOutgoing = "outgoing"
+	directionBoth     = "both"
+)
+
+type listTransfersRequest struct {
+	Cursor    int64     `form:"cursor" binding:"min=0"`
+	PageSize  int32     `form:"page_size" binding:"required,min=5,max=100"`
+	Direction string    `form:"direction" binding:"omitempty,oneof=incoming outgoing both"`
+	From      time.Time `form:"from"`
+	To        time.Time `form:"to"`
+	// MinAmount and MaxAmount are in the account currency
+	MinAmount string `form:"min_amount"`
+	MaxAmount string `form:"max_amount"`
+}
+
+type listTransfersResponse struct {
+	Transfers  []transferResponse `json:"transfers"`
+	NextCursor int64              `json:"next_cursor,omitempty"`
+}
+
+// listTransfers pages through the account transfers by id, pass next_cursor back as cursor to get the next page
+func (s *Server) listTransfers(c *gin.Context) {
+	var req listTransfersRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		err := errors.New("from must be before to")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.Direction == "" {
+		req.Direction = directionBoth
+	}
+
+	account := c.MustGet(accountKey).(db.Account)
+	currency, ok := s.currency(c, account.Currency)
+	if !ok {
+		return
+	}
+
+	minAmount, ok := parseAmountFilter(c, req.MinAmount, currency)
+	if !ok {
+		return
+	}
+
+	maxAmount, ok := parseAmountFilter(c, req.MaxAmount, currency)
+	if !ok {
+		return
+	}
+
+	if minAmount.Valid && maxAmount.Valid && minAmount.Int64 > maxAmount.Int64 {
+		err := errors.New("min_amount must not exceed max_amount")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListAccountTransfersParams{
+		AccountID: account.ID,
+		Outgoing:  req.Direction != directionIncoming,
+		Incoming:  req.Direction != directionOutgoing,
+		AfterID:   req.Cursor,
+		FromTime:  sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		ToTime:    sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		MinAmount: minAmount,
+		MaxAmount: maxAmount,
+		// one extra row tells whether there is a next page
+		PageSize: req.PageSize + 1,
+	}
+
+	rows, err := s.store.ListAccountTransfers(c, arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var rsp listTransfersResponse
+	if len(rows) > int(req.PageSize) {
+		rows = rows[:req.PageSize]
+		rsp.NextCursor = rows[req.PageSize-1].Transfer.ID
+	}
+
+	rsp.Transfers = make([]transferResponse, len(rows))
+	for i, row := range rows {
+		from, ok := s.currency(c, row.FromCurrency)
+		if !ok {
+			return
+		}
+
+		to, ok := s.currency(c, row.ToCurrency)
+		if !ok {
+			return
+		}
+
+		rsp.Transfers[i] = newTransferResponse(row.Transfer, from, to)
+	}
+
+	c.JSON(http.StatusOK, rsp)
+}
+
+// parseAmountFilter reads an optional non-negative amount, an empty value disables the filter
+func parseAmountFilter(c *gin.Context, value string, currency util.Currency) (sql.NullInt64, bool) {
+	if value == "" {
+		return sql.NullInt64{}, true
+	}
+
+	amount, ok := parseAmount(c, value, currency)
+	if !ok {
+		return sql.NullInt64{}, false
+	}
+
+	if amount.Amount < 0 {
+		err := errors.New("amount filters must not be negative")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return sql.NullInt64{}, false
+	}
+
+	return sql.NullInt64{Int64: amount.Amount, Valid: true}, true
 }
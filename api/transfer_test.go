@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"github.com/stretchr/testify/require"
 	mockdb "github.com/vadym-98/simple_bank/db/mock"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
@@ -9,8 +11,10 @@ import (
 	"github.com/vadym-98/simple_bank/util"
 	"github.com/vadym-98/simple_bank/util/faker"
 	"go.uber.org/mock/gomock"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 )
@@ -21,7 +25,10 @@ func TestCreateTransferAPI(t *testing.T) {
 
 	a1 := faker.NewAccount().WithOwner(u1.Username).WithCurrency(util.USD).Get()
 	a2 := faker.NewAccount().WithOwner(u2.Username).WithCurrency(util.USD).Get()
+	jpyAccount := faker.NewAccount().WithOwner(u1.Username).WithCurrency("JPY").Get()
+	usd := testCurrency(util.USD)
 	transfer := faker.NewTransfer().WithFromAccountID(a1.ID).WithToAccountID(a2.ID).Get()
+	transfer.Amount, transfer.ToAmount = 1230, 1230
 	tr := db.TransferTxResult{
 		Transfer:    transfer,
 		FromAccount: a1,
@@ -32,7 +39,7 @@ func TestCreateTransferAPI(t *testing.T) {
 	stdTransReq := transferRequest{
 		FromAccountID: a1.ID,
 		ToAccountID:   a2.ID,
-		Amount:        transfer.Amount,
+		Amount:        util.NewMoney(transfer.Amount, usd).String(),
 		Currency:      util.USD,
 	}
 
@@ -47,7 +54,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			name: "OK",
 			body: stdTransReq,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -69,14 +76,14 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
-				requireBodyMatchStruct[db.TransferTxResult](t, recorder.Body, tr)
+				requireBodyMatchStruct[transferTxResponse](t, recorder.Body, newTransferTxResponse(tr, usd, usd))
 			},
 		},
 		{
 			name: "InternalError",
 			body: stdTransReq,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -100,11 +107,111 @@ func TestCreateTransferAPI(t *testing.T) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name: "TxRetriesExhausted",
+			body: stdTransReq,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w after 3 attempts", db.ErrTxRetriesExhausted))
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a1.ID)).
+					Times(1).
+					Return(a1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a2.ID)).
+					Times(1).
+					Return(a2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: stdTransReq,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: a1.ID,
+						ToAccountID:   a2.ID,
+						Amount:        transfer.Amount,
+					})).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a1.ID)).
+					Times(1).
+					Return(a1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a2.ID)).
+					Times(1).
+					Return(a2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "SystemAccount",
+			body: stdTransReq,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrSystemAccount)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a1.ID)).
+					Times(1).
+					Return(a1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a2.ID)).
+					Times(1).
+					Return(a2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "BalanceOverflow",
+			body: stdTransReq,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrBalanceOverflow)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a1.ID)).
+					Times(1).
+					Return(a1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a2.ID)).
+					Times(1).
+					Return(a2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
 		{
 			name: "FromAccountIDNotFound",
 			body: stdTransReq,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -130,7 +237,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			name: "GetFromAccountIDInternalError",
 			body: stdTransReq,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -157,11 +264,11 @@ func TestCreateTransferAPI(t *testing.T) {
 			body: transferRequest{
 				FromAccountID: a1.ID,
 				ToAccountID:   a2.ID,
-				Amount:        transfer.Amount,
+				Amount:        util.NewMoney(transfer.Amount, usd).String(),
 				Currency:      util.EUR,
 			},
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -184,30 +291,88 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "ToAccountIDInvalidCurrency",
+			name: "CrossCurrency",
 			body: stdTransReq,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				toAccInvalidCurrency := a2
-				toAccInvalidCurrency.Currency = util.EUR
+				eurAccount := a2
+				eurAccount.Currency = util.EUR
 
 				store.EXPECT().
 					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
 						FromAccountID: a1.ID,
 						ToAccountID:   a2.ID,
 						Amount:        transfer.Amount,
+						ToAmount:      int64(math.Round(float64(transfer.Amount) * 0.5)),
+						ExchangeRate:  0.5,
 					})).
+					Times(1).
+					Return(tr, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a1.ID)).
+					Times(1).
+					Return(a1, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(eurAccount.ID)).
+					Times(1).
+					Return(eurAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedCurrencyPair",
+			body: stdTransReq,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				cadAccount := a2
+				cadAccount.Currency = util.CAD
+
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
 					Times(0)
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(a1.ID)).
 					Times(1).
 					Return(a1, nil)
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(toAccInvalidCurrency.ID)).
+					GetAccount(gomock.Any(), gomock.Eq(cadAccount.ID)).
 					Times(1).
-					Return(toAccInvalidCurrency, nil)
+					Return(cadAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			// a yen is worth 0.4 cents
+			name: "ConvertsToZero",
+			body: transferRequest{
+				FromAccountID: jpyAccount.ID,
+				ToAccountID:   a2.ID,
+				Amount:        "1",
+				Currency:      "JPY",
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(jpyAccount.ID)).
+					Times(1).
+					Return(jpyAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(a2.ID)).
+					Times(1).
+					Return(a2, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
@@ -218,11 +383,11 @@ func TestCreateTransferAPI(t *testing.T) {
 			body: transferRequest{
 				FromAccountID: a1.ID,
 				ToAccountID:   a2.ID,
-				Amount:        transfer.Amount,
+				Amount:        util.NewMoney(transfer.Amount, usd).String(),
 				Currency:      "invalid",
 			},
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -247,7 +412,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			name: "Unauthorized",
 			body: stdTransReq,
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u2.Username, time.Minute)
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u2.Username, util.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -287,3 +452,525 @@ func TestCreateTransferAPI(t *testing.T) {
 		})
 	}
 }
+
+// TestCreateTransferAPIRequestTimeout checks the request deadline reaches the store, so a slow transfer is cancelled
+func TestCreateTransferAPIRequestTimeout(t *testing.T) {
+	user := faker.NewUser().Get()
+	a1 := faker.NewAccount().WithOwner(user.Username).WithCurrency(util.USD).Get()
+	a2 := faker.NewAccount().WithCurrency(util.USD).Get()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+			<-ctx.Done()
+			return db.TransferTxResult{}, ctx.Err()
+		})
+
+	server := newTestServer(t, store)
+	server.config.RequestTimeout = 50 * time.Millisecond
+	server.setupRouter()
+
+	body := transferRequest{FromAccountID: a1.ID, ToAccountID: a2.ID, Amount: "1.00", Currency: util.USD}
+	req, err := http.NewRequest(http.MethodPost, "/transfers", createBody(t, body))
+	require.NoError(t, err)
+
+	addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+// TestCreateTransferAPIRequireVerifiedEmail checks transfers are rejected before the store is touched
+// while the config requires a verified email
+func TestCreateTransferAPIRequireVerifiedEmail(t *testing.T) {
+	user := faker.NewUser().Get()
+	verified := user
+	verified.IsEmailVerified = true
+	a1 := faker.NewAccount().WithOwner(user.Username).WithCurrency(util.USD).Get()
+	a2 := faker.NewAccount().WithCurrency(util.USD).Get()
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Verified",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(verified, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotVerified",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.RequireVerifiedEmail = true
+			server.setupRouter()
+
+			body := transferRequest{FromAccountID: a1.ID, ToAccountID: a2.ID, Amount: "1.00", Currency: util.USD}
+			req, err := http.NewRequest(http.MethodPost, "/transfers", createBody(t, body))
+			require.NoError(t, err)
+
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateTransferIdempotencyAPI(t *testing.T) {
+	u1 := faker.NewUser().Get()
+	u2 := faker.NewUser().Get()
+
+	a1 := faker.NewAccount().WithOwner(u1.Username).WithCurrency(util.USD).Get()
+	a2 := faker.NewAccount().WithOwner(u2.Username).WithCurrency(util.USD).Get()
+	usd := testCurrency(util.USD)
+	transfer := faker.NewTransfer().WithFromAccountID(a1.ID).WithToAccountID(a2.ID).Get()
+	transfer.Amount, transfer.ToAmount = 1230, 1230
+	tr := db.TransferTxResult{
+		Transfer:    transfer,
+		FromAccount: a1,
+		ToAccount:   a2,
+		FromEntry:   faker.NewEntry().WithAccountID(a1.ID).WithAmount(-transfer.Amount).Get(),
+		ToEntry:     faker.NewEntry().WithAccountID(a2.ID).WithAmount(transfer.Amount).Get(),
+	}
+	key := util.RandomString(16)
+
+	requestHash, err := hashRequest(idempotentTransfer{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Currency:      util.USD,
+		Amount:        transfer.Amount,
+	})
+	require.NoError(t, err)
+
+	txParams := db.IdempotentTransferTxParams{
+		TransferTxParams: db.TransferTxParams{
+			FromAccountID: a1.ID,
+			ToAccountID:   a2.ID,
+			Amount:        transfer.Amount,
+		},
+		Username:    u1.Username,
+		Key:         key,
+		RequestHash: requestHash,
+	}
+
+	testCases := []struct {
+		name          string
+		amount        string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			amount: "12.30",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Eq(txParams)).
+					Times(1).
+					Return(tr, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[transferTxResponse](t, recorder.Body, newTransferTxResponse(tr, usd, usd))
+			},
+		},
+		{
+			// the replay writes the amount differently, it is still the same request
+			name:   "EquivalentAmount",
+			amount: "12.3",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Eq(txParams)).
+					Times(1).
+					Return(tr, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[transferTxResponse](t, recorder.Body, newTransferTxResponse(tr, usd, usd))
+			},
+		},
+		{
+			name:   "KeyConflict",
+			amount: "12.30",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Eq(txParams)).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrIdempotencyKeyConflict)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body := transferRequest{
+				FromAccountID: a1.ID,
+				ToAccountID:   a2.ID,
+				Amount:        tc.amount,
+				Currency:      util.USD,
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/transfers", createBody(t, body))
+			require.NoError(t, err)
+			req.Header.Set(idempotencyKeyHeader, key)
+
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetTransferAPI(t *testing.T) {
+	u1 := faker.NewUser().Get()
+	u2 := faker.NewUser().Get()
+
+	a1 := faker.NewAccount().WithOwner(u1.Username).Get()
+	a2 := faker.NewAccount().WithOwner(u2.Username).Get()
+	transfer := faker.NewTransfer().WithFromAccountID(a1.ID).WithToAccountID(a2.ID).Get()
+	expected := newTransferResponse(transfer, testCurrency(a1.Currency), testCurrency(a2.Currency))
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		setupAuth     func(t *testing.T, req *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "SenderOK",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[transferResponse](t, recorder.Body, expected)
+			},
+		},
+		{
+			name:       "RecipientOK",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u2.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[transferResponse](t, recorder.Body, expected)
+			},
+		},
+		{
+			name:       "UnauthorizedUser",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidID",
+			transferID: 0,
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, u1.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d", tc.transferID)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListTransfersAPI(t *testing.T) {
+	user := faker.NewUser().Get()
+	account := faker.NewAccount().WithOwner(user.Username).Get()
+
+	pageSize := 5
+	transfers := make([]db.ListAccountTransfersRow, pageSize+1)
+	transfersResponse := make([]transferResponse, pageSize+1)
+	for i := range transfers {
+		transfers[i] = db.ListAccountTransfersRow{
+			Transfer:     faker.NewTransfer().WithToAccountID(account.ID).Get(),
+			FromCurrency: util.USD,
+			ToCurrency:   account.Currency,
+		}
+		transfers[i].Transfer.ID = int64(i + 1)
+		transfersResponse[i] = newTransferResponse(transfers[i].Transfer, testCurrency(util.USD), testCurrency(account.Currency))
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, req *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Eq(db.ListAccountTransfersParams{
+						AccountID: account.ID,
+						Outgoing:  true,
+						Incoming:  true,
+						PageSize:  int32(pageSize + 1),
+					})).
+					Times(1).
+					Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[listTransfersResponse](t, recorder.Body, listTransfersResponse{
+					Transfers:  transfersResponse[:pageSize],
+					NextCursor: transfers[pageSize-1].Transfer.ID,
+				})
+			},
+		},
+		{
+			name: "IncomingWithAmountRange",
+			query: url.Values{
+				"page_size":  {fmt.Sprint(pageSize)},
+				"direction":  {directionIncoming},
+				"min_amount": {"0.10"},
+				"max_amount": {"1.00"},
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Eq(db.ListAccountTransfersParams{
+						AccountID: account.ID,
+						Incoming:  true,
+						MinAmount: sql.NullInt64{Int64: 10, Valid: true},
+						MaxAmount: sql.NullInt64{Int64: 100, Valid: true},
+						PageSize:  int32(pageSize + 1),
+					})).
+					Times(1).
+					Return(transfers[:2], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[listTransfersResponse](t, recorder.Body, listTransfersResponse{
+					Transfers: transfersResponse[:2],
+				})
+			},
+		},
+		{
+			name: "InvalidDirection",
+			query: url.Values{
+				"page_size": {fmt.Sprint(pageSize)},
+				"direction": {"sideways"},
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAmountRange",
+			query: url.Values{
+				"page_size":  {fmt.Sprint(pageSize)},
+				"min_amount": {"1.00"},
+				"max_amount": {"0.10"},
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeAmountFilter",
+			query: url.Values{
+				"page_size":  {fmt.Sprint(pageSize)},
+				"min_amount": {"-1"},
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "UnauthorizedUser",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/transfers?%s", account.ID, tc.query.Encode())
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
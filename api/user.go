@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/token"
 	util "github.com/vadym-98/simple_bank/util"
 	"net/http"
 	"time"
@@ -22,6 +25,7 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -31,6 +35,7 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -56,7 +61,14 @@ func (s *Server) createUser(c *gin.Context) {
 		Email:          req.Email,
 	}
 
-	user, err := s.store.CreateUser(c, arg)
+	result, err := s.store.CreateUserTx(c, db.CreateUserTxParams{
+		CreateUserParams: arg,
+		// a lost email can be sent again through /users/verify_email/resend
+		AfterCommit: func(user db.User, verifyEmail db.VerifyEmail) error {
+			s.sendInBackground(mail.VerifyEmailMessage(s.config.VerifyEmailURL, user, verifyEmail))
+			return nil
+		},
+	})
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
@@ -69,19 +81,83 @@ func (s *Server) createUser(c *gin.Context) {
 		return
 	}
 
-	rp := newUserResponse(user)
+	rp := newUserResponse(result.User)
 
 	c.JSON(http.StatusOK, rp)
 }
 
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+// verifyEmail is opened from the link of the verification email, it needs no access token
+func (s *Server) verifyEmail(c *gin.Context) {
+	var req verifyEmailRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := s.store.VerifyEmailTx(c, db.VerifyEmailTxParams{
+		EmailID:    req.EmailID,
+		SecretCode: req.SecretCode,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrVerifyEmailInvalid) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+// resendVerifyEmail mails a new verification code to the authenticated user, for when the first email got lost
+func (s *Server) resendVerifyEmail(c *gin.Context) {
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	_, err := s.store.ResendVerifyEmailTx(c, db.ResendVerifyEmailTxParams{
+		Username: authPayload.Username,
+		AfterCommit: func(user db.User, verifyEmail db.VerifyEmail) error {
+			s.sendInBackground(mail.VerifyEmailMessage(s.config.VerifyEmailURL, user, verifyEmail))
+			return nil
+		},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrEmailAlreadyVerified) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
 type loginUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
 	Password string `json:"password" binding:"required,min=6"`
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (s *Server) loginUser(c *gin.Context) {
@@ -108,15 +184,84 @@ func (s *Server) loginUser(c *gin.Context) {
 		return
 	}
 
-	token, err := s.tokenMaker.CreateToken(req.Username, s.config.AccessTokenDuration)
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccessToken, s.config.AccessTokenDuration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	refreshToken, refreshPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefreshToken, s.config.RefreshTokenDuration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	session, err := s.store.CreateSession(c, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    c.Request.UserAgent(),
+		ClientIp:     c.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	rsp := loginUserResponse{
-		AccessToken: token,
-		User:        newUserResponse(user),
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
 	}
 	c.JSON(http.StatusOK, rsp)
 }
+
+type logoutUserRequest struct {
+	SessionID string `json:"session_id" binding:"required,uuid"`
+}
+
+// logoutUser blocks the session returned by the login, so its refresh token can't renew access tokens anymore,
+// and revokes the access token used for the request, so it can't be used again until it expires
+func (s *Server) logoutUser(c *gin.Context) {
+	var req logoutUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	sessionID := uuid.MustParse(req.SessionID)
+	session, err := s.store.GetSession(c, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if session.Username != authPayload.Username {
+		err := errors.New("session doesn't belong to the authenticated user")
+		c.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	if _, err := s.store.BlockSession(c, sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := s.revocations.Revoke(c, authPayload); err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -1,13 +1,18 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	mockdb "github.com/vadym-98/simple_bank/db/mock"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/token"
 	mocktoken "github.com/vadym-98/simple_bank/token/mock"
 	"github.com/vadym-98/simple_bank/util"
 	"github.com/vadym-98/simple_bank/util/faker"
@@ -19,16 +24,17 @@ import (
 	"time"
 )
 
-type eqCreateUserParamsMatcher struct {
+type eqCreateUserTxParamsMatcher struct {
 	arg      db.CreateUserParams
 	password string
 }
 
-func (e eqCreateUserParamsMatcher) Matches(x any) bool {
-	arg, ok := x.(db.CreateUserParams)
+func (e eqCreateUserTxParamsMatcher) Matches(x any) bool {
+	txArg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
+	arg := txArg.CreateUserParams
 
 	err := util.CheckPassword(e.password, arg.HashedPassword)
 	if err != nil {
@@ -39,12 +45,12 @@ func (e eqCreateUserParamsMatcher) Matches(x any) bool {
 	return reflect.DeepEqual(e.arg, arg)
 }
 
-func (e eqCreateUserParamsMatcher) String() string {
+func (e eqCreateUserTxParamsMatcher) String() string {
 	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
 }
 
-func EqCreateUserParams(arg db.CreateUserParams, pwd string) gomock.Matcher {
-	return eqCreateUserParamsMatcher{arg: arg, password: pwd}
+func EqCreateUserTxParams(arg db.CreateUserParams, pwd string) gomock.Matcher {
+	return eqCreateUserTxParamsMatcher{arg: arg, password: pwd}
 }
 
 func TestCreateUserAPI(t *testing.T) {
@@ -61,6 +67,13 @@ func TestCreateUserAPI(t *testing.T) {
 		FullName: u.FullName,
 		Email:    u.Email,
 	}
+	verifyEmail := db.VerifyEmail{
+		ID:         util.RandomInt(1, 1000),
+		Username:   u.Username,
+		Email:      u.Email,
+		SecretCode: util.RandomString(32),
+		ExpiredAt:  time.Now().Add(15 * time.Minute),
+	}
 	uResp := userResponse{
 		Username: u.Username,
 		FullName: u.FullName,
@@ -71,18 +84,48 @@ func TestCreateUserAPI(t *testing.T) {
 		name          string
 		body          createUserRequest
 		buildStubs    func(store *mockdb.MockStore)
+		mailer        mail.Mailer
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+		checkMailer   func(t *testing.T, mailer *mail.MemoryMailer)
 	}{
 		{
 			name: "OK",
 			body: stdUreq,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUserParams(uParams, pwd)).
+					CreateUserTx(gomock.Any(), EqCreateUserTxParams(uParams, pwd)).
 					Times(1).
-					Return(u, nil)
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						result := db.CreateUserTxResult{User: u, VerifyEmail: verifyEmail}
+						return result, arg.AfterCommit(result.User, result.VerifyEmail)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[userResponse](t, recorder.Body, uResp)
+			},
+			checkMailer: func(t *testing.T, mailer *mail.MemoryMailer) {
+				sent := mailer.Sent()
+				require.Len(t, sent, 1)
+				require.Equal(t, []string{u.Email}, sent[0].To)
+				require.Contains(t, sent[0].Body, verifyEmail.SecretCode)
+			},
+		},
+		{
+			name: "SendEmailFailed",
+			body: stdUreq,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						result := db.CreateUserTxResult{User: u, VerifyEmail: verifyEmail}
+						return result, arg.AfterCommit(result.User, result.VerifyEmail)
+					})
 			},
+			mailer: failingMailer{},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// the user was created, only the email has to be sent again
 				require.Equal(t, http.StatusOK, recorder.Code)
 				requireBodyMatchStruct[userResponse](t, recorder.Body, uResp)
 			},
@@ -92,9 +135,9 @@ func TestCreateUserAPI(t *testing.T) {
 			body: stdUreq,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, sql.ErrConnDone)
+					Return(db.CreateUserTxResult{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
@@ -110,7 +153,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -122,9 +165,9 @@ func TestCreateUserAPI(t *testing.T) {
 			body: stdUreq,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, &pq.Error{Code: "23505"})
+					Return(db.CreateUserTxResult{}, &pq.Error{Code: "23505"})
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusForbidden, recorder.Code)
@@ -140,7 +183,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -157,7 +200,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -174,7 +217,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -194,11 +237,227 @@ func TestCreateUserAPI(t *testing.T) {
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
+			if tc.mailer != nil {
+				server.mailer = tc.mailer
+			}
+
 			req, err := http.NewRequest(http.MethodPost, "/users", createBody(t, tc.body))
 			require.NoError(t, err)
 
 			server.router.ServeHTTP(recorder, req)
 			tc.checkResponse(t, recorder)
+
+			// the email goes out after the response
+			server.pendingMails.Wait()
+			if tc.checkMailer != nil {
+				tc.checkMailer(t, server.mailer.(*mail.MemoryMailer))
+			}
+		})
+	}
+}
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user := faker.NewUser().Get()
+	user.IsEmailVerified = true
+	verifyEmail := db.VerifyEmail{
+		ID:         util.RandomInt(1, 1000),
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		IsUsed:     true,
+	}
+	arg := db.VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: fmt.Sprintf("email_id=%d&secret_code=%s", arg.EmailID, arg.SecretCode),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.VerifyEmailTxResult{User: user, VerifyEmail: verifyEmail}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStruct[verifyEmailResponse](t, recorder.Body, verifyEmailResponse{IsVerified: true})
+			},
+		},
+		{
+			name:  "InvalidCode",
+			query: fmt.Sprintf("email_id=%d&secret_code=%s", arg.EmailID, arg.SecretCode),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, db.ErrVerifyEmailInvalid)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingSecretCode",
+			query: fmt.Sprintf("email_id=%d", arg.EmailID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: fmt.Sprintf("email_id=%d&secret_code=%s", arg.EmailID, arg.SecretCode),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/users/verify_email?"+tc.query, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResendVerifyEmailAPI(t *testing.T) {
+	user := faker.NewUser().Get()
+	verifyEmail := db.VerifyEmail{
+		ID:         util.RandomInt(1, 1000),
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		ExpiredAt:  time.Now().Add(15 * time.Minute),
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, req *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+		checkMailer   func(t *testing.T, mailer *mail.MemoryMailer)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResendVerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResendVerifyEmailTxParams) (db.VerifyEmail, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return verifyEmail, arg.AfterCommit(user, verifyEmail)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+			checkMailer: func(t *testing.T, mailer *mail.MemoryMailer) {
+				sent := mailer.Sent()
+				require.Len(t, sent, 1)
+				require.Contains(t, sent[0].Body, verifyEmail.SecretCode)
+			},
+		},
+		{
+			name: "AlreadyVerified",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResendVerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmail{}, db.ErrEmailAlreadyVerified)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResendVerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, req, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResendVerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmail{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodPost, "/users/verify_email/resend", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+
+			server.pendingMails.Wait()
+			if tc.checkMailer != nil {
+				tc.checkMailer(t, server.mailer.(*mail.MemoryMailer))
+			}
 		})
 	}
 }
@@ -233,6 +492,20 @@ func TestLoginUserAPI(t *testing.T) {
 					GetUser(gomock.Any(), gomock.Eq(u.Username)).
 					Times(1).
 					Return(u, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateSessionParams) (db.Session, error) {
+						require.Equal(t, u.Username, arg.Username)
+						require.NotEmpty(t, arg.RefreshToken)
+						require.False(t, arg.IsBlocked)
+						return db.Session{
+							ID:           arg.ID,
+							Username:     arg.Username,
+							RefreshToken: arg.RefreshToken,
+							ExpiresAt:    arg.ExpiresAt,
+						}, nil
+					})
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -340,9 +613,29 @@ func TestLoginUserAPI(t *testing.T) {
 					Return(u, nil)
 
 				maker.EXPECT().
-					CreateToken(gomock.Eq(u.Username), gomock.Eq(duration)).
+					CreateToken(gomock.Eq(u.Username), gomock.Eq(u.Role), gomock.Eq(token.TokenTypeAccessToken), gomock.Eq(duration)).
+					Times(1).
+					Return("", nil, errors.New("failed to encode payload to []byte"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "FailedCreateSession",
+			body: lReq,
+			setUpServer: func(t *testing.T, store *mockdb.MockStore, maker *mocktoken.MockMaker) *Server {
+				return newTestServer(t, store)
+			},
+			buildStubs: func(store *mockdb.MockStore, maker *mocktoken.MockMaker, duration time.Duration) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(u.Username)).
 					Times(1).
-					Return("", errors.New("failed to encode payload to []byte"))
+					Return(u, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
@@ -371,3 +664,66 @@ func TestLoginUserAPI(t *testing.T) {
 		})
 	}
 }
+
+func TestLogoutUserAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	srv := newTestServer(t, store)
+	user := faker.NewUser().Get()
+
+	refreshToken, refreshPayload, err := srv.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefreshToken, time.Hour)
+	require.NoError(t, err)
+	session := newTestSession(refreshToken, refreshPayload)
+
+	// the mocked store keeps the session, so the renewal sees it blocked by the logout
+	store.EXPECT().
+		GetSession(gomock.Any(), gomock.Eq(session.ID)).
+		AnyTimes().
+		DoAndReturn(func(context.Context, uuid.UUID) (db.Session, error) {
+			return session, nil
+		})
+	store.EXPECT().
+		BlockSession(gomock.Any(), gomock.Eq(session.ID)).
+		Times(1).
+		DoAndReturn(func(context.Context, uuid.UUID) (db.Session, error) {
+			session.IsBlocked = true
+			return session, nil
+		})
+
+	accessToken, _, err := srv.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+	logout := func(accessToken string, body any) int {
+		rq, err := http.NewRequest(http.MethodPost, "/users/logout", createBody(t, body))
+		require.NoError(t, err)
+		if accessToken != "" {
+			rq.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+		}
+
+		recorder := httptest.NewRecorder()
+		srv.router.ServeHTTP(recorder, rq)
+		return recorder.Code
+	}
+	logoutBody := logoutUserRequest{SessionID: session.ID.String()}
+
+	require.Equal(t, http.StatusBadRequest, logout(accessToken, gin.H{}))
+	require.Equal(t, http.StatusUnauthorized, logout("", logoutBody))
+
+	// the session of another user can't be blocked
+	otherToken, _, err := srv.tokenMaker.CreateToken("other", util.DepositorRole, token.TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, logout(otherToken, logoutBody))
+
+	require.Equal(t, http.StatusOK, logout(accessToken, logoutBody))
+
+	// the same access token must be rejected once revoked
+	require.Equal(t, http.StatusUnauthorized, logout(accessToken, logoutBody))
+
+	// and the refresh token of the session can't renew it
+	rq, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", createBody(t, renewAccessTokenRequest{RefreshToken: refreshToken}))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	srv.router.ServeHTTP(recorder, rq)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
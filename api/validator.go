@@ -1,13 +1,14 @@
 package api
 
 import (
+	"context"
 	"github.com/go-playground/validator/v10"
-	"github.com/vadym-98/simple_bank/util"
 )
 
-var validCurrency validator.Func = func(f validator.FieldLevel) bool {
+// validCurrency accepts currencies enabled in the registry
+func (s *Server) validCurrency(f validator.FieldLevel) bool {
 	if currency, ok := f.Field().Interface().(string); ok {
-		return util.IsSupportedCurrency(currency)
+		return s.currencies.IsSupported(context.Background(), currency)
 	}
 
 	return false
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/webhook"
+	"net/http"
+	"time"
+)
+
+type webhookResponse struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"account_id"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(hook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:        hook.ID,
+		AccountID: hook.AccountID,
+		URL:       hook.Url,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+// createWebhookResponse is the only response carrying the secret, receivers need it to verify signatures
+type createWebhookResponse struct {
+	webhookResponse
+	Secret string `json:"secret"`
+}
+
+type createWebhookRequest struct {
+	AccountID int64  `json:"account_id" binding:"required,min=1"`
+	URL       string `json:"url" binding:"required,url"`
+}
+
+// createWebhook registers an endpoint that receives the transfers and balance changes of one of the user's accounts
+// The url must resolve to public addresses only, so webhooks can't be used to reach the bank's own network
+func (s *Server) createWebhook(c *gin.Context) {
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := s.webhookURLs.Check(c, req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := s.findAccount(c, req.AccountID)
+	if !valid {
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesn't belong to the authenticated user")
+		c.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	hook, err := s.store.CreateWebhook(c, db.CreateWebhookParams{
+		Owner:     authPayload.Username,
+		AccountID: account.ID,
+		Url:       req.URL,
+		Secret:    secret,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, createWebhookResponse{
+		webhookResponse: newWebhookResponse(hook),
+		Secret:          hook.Secret,
+	})
+}
+
+type listWebhooksRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (s *Server) listWebhooks(c *gin.Context) {
+	var req listWebhooksRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	hooks, err := s.store.ListWebhooks(c, db.ListWebhooksParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]webhookResponse, len(hooks))
+	for i, hook := range hooks {
+		rsp[i] = newWebhookResponse(hook)
+	}
+
+	c.JSON(http.StatusOK, rsp)
+}
+
+func (s *Server) getWebhook(c *gin.Context) {
+	hook := c.MustGet(webhookKey).(db.Webhook)
+	c.JSON(http.StatusOK, newWebhookResponse(hook))
+}
+
+// deleteWebhook removes the webhook along with its deliveries, pending ones are never sent
+func (s *Server) deleteWebhook(c *gin.Context) {
+	hook := c.MustGet(webhookKey).(db.Webhook)
+
+	if err := s.store.DeleteWebhook(c, hook.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type webhookDeliveryResponse struct {
+	ID             int64                    `json:"id"`
+	EventType      string                   `json:"event_type"`
+	Payload        json.RawMessage          `json:"payload"`
+	Status         db.WebhookDeliveryStatus `json:"status"`
+	Attempts       int32                    `json:"attempts"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at,omitempty"`
+	ResponseStatus int32                    `json:"response_status,omitempty"`
+	LastError      string                   `json:"last_error,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	DeliveredAt    *time.Time               `json:"delivered_at,omitempty"`
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) webhookDeliveryResponse {
+	rsp := webhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == db.WebhookDeliveryStatusPending {
+		rsp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		rsp.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return rsp
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listWebhookDeliveries is the delivery log of the webhook, the latest first
+func (s *Server) listWebhookDeliveries(c *gin.Context) {
+	var req listWebhookDeliveriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hook := c.MustGet(webhookKey).(db.Webhook)
+	deliveries, err := s.store.ListWebhookDeliveries(c, db.ListWebhookDeliveriesParams{
+		WebhookID: hook.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]webhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		rsp[i] = newWebhookDeliveryResponse(delivery)
+	}
+
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,13 @@
+CREATE TYPE "account_status" AS ENUM (
+    'active',
+    'frozen',
+    'closed'
+);
+
+ALTER TABLE "accounts"
+    ADD COLUMN "status" account_status NOT NULL DEFAULT 'active';
+
+-- closed accounts keep their history, so they shouldn't block opening a new one in the same currency
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "owner_currency_key";
+
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency") WHERE "status" <> 'closed';
@@ -0,0 +1 @@
+CREATE INDEX "entries_account_id_id_idx" ON "entries" ("account_id", "id");
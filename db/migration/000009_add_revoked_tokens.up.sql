@@ -0,0 +1,9 @@
+CREATE TABLE "revoked_tokens"
+(
+    "id"         uuid PRIMARY KEY,
+    "username"   varchar     NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "revoked_tokens" ("expires_at");
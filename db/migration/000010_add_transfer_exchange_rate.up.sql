@@ -0,0 +1,16 @@
+ALTER TABLE "transfers"
+    ADD COLUMN "to_amount" bigint;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers"
+    ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers"
+    ADD COLUMN "exchange_rate" double precision NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN "transfers"."amount" IS 'must be positive, in the currency of the source account';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'credited to the destination account, in its currency';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'units of the destination currency per unit of the source currency';
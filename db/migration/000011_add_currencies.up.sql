@@ -0,0 +1,19 @@
+CREATE TABLE "currencies"
+(
+    "code"       varchar(3) PRIMARY KEY,
+    "exponent"   smallint    NOT NULL DEFAULT 2,
+    "enabled"    boolean     NOT NULL DEFAULT true,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT "exponent_non_negative" CHECK ("exponent" >= 0)
+);
+
+COMMENT ON COLUMN "currencies"."code" IS 'ISO 4217 alphabetic code';
+
+COMMENT ON COLUMN "currencies"."exponent" IS 'number of minor unit digits, 2 for cents';
+
+INSERT INTO "currencies" ("code", "exponent")
+VALUES ('USD', 2),
+       ('EUR', 2),
+       ('CAD', 2);
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
@@ -0,0 +1,19 @@
+DELETE FROM "entries" WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "kind" <> 'customer');
+
+DELETE FROM "accounts" WHERE "kind" <> 'customer';
+
+DELETE FROM "users" WHERE "username" = '_bank';
+
+DROP INDEX IF EXISTS "owner_currency_key";
+
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency") WHERE "status" <> 'closed';
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "kind";
+
+DROP TYPE IF EXISTS "account_kind";
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "journal_entry_id";
+
+DROP TABLE IF EXISTS "journal_entries";
+
+DROP TYPE IF EXISTS "journal_kind";
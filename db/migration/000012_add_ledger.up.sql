@@ -0,0 +1,45 @@
+CREATE TYPE "journal_kind" AS ENUM (
+    'transfer',
+    'deposit',
+    'withdrawal',
+    'adjustment',
+    'fee'
+);
+
+CREATE TABLE "journal_entries"
+(
+    "id"         bigserial PRIMARY KEY,
+    "kind"       journal_kind NOT NULL,
+    "created_at" timestamptz  NOT NULL DEFAULT (now())
+);
+
+-- entries are the postings of a journal entry, the postings of one journal entry sum to zero per currency
+-- entries written before the ledger existed don't belong to any journal entry
+ALTER TABLE "entries"
+    ADD COLUMN "journal_entry_id" bigint REFERENCES "journal_entries" ("id");
+
+CREATE INDEX ON "entries" ("journal_entry_id");
+
+CREATE TYPE "account_kind" AS ENUM (
+    'customer',
+    'fees',
+    'suspense'
+);
+
+ALTER TABLE "accounts"
+    ADD COLUMN "kind" account_kind NOT NULL DEFAULT 'customer';
+
+COMMENT ON COLUMN "accounts"."kind" IS 'system accounts hold the other side of fees, deposits, withdrawals and currency exchanges';
+
+DROP INDEX IF EXISTS "owner_currency_key";
+
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency", "kind") WHERE "status" <> 'closed';
+
+-- the system user can't log in: its password hash is empty and the API rejects usernames with an underscore prefix
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email", "role")
+VALUES ('_bank', '', 'Simple Bank', 'ledger@simplebank.invalid', 'system');
+
+INSERT INTO "accounts" ("owner", "balance", "currency", "kind")
+SELECT '_bank', 0, "code", "kind"
+FROM "currencies"
+         CROSS JOIN (VALUES ('fees'::account_kind), ('suspense'::account_kind)) AS "kinds" ("kind");
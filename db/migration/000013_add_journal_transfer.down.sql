@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS "journal_entries" DROP COLUMN IF EXISTS "transfer_id";
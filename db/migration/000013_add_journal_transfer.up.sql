@@ -0,0 +1,5 @@
+-- transfers written before the ledger existed have no journal entry, reconciliation reports them as unmatched
+ALTER TABLE "journal_entries"
+    ADD COLUMN "transfer_id" bigint REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "journal_entries" ("transfer_id");
@@ -0,0 +1,51 @@
+CREATE TYPE "entry_type" AS ENUM (
+    'transfer_debit',
+    'transfer_credit',
+    'deposit',
+    'withdrawal',
+    'fee',
+    'interest',
+    'adjustment'
+);
+
+ALTER TABLE "entries"
+    ADD COLUMN "transfer_id" bigint REFERENCES "transfers" ("id"),
+    ADD COLUMN "entry_type"  entry_type;
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+-- postings of the ledger take their transfer and type from the journal entry
+UPDATE "entries"
+SET "transfer_id" = "journal_entries"."transfer_id",
+    "entry_type"  = (CASE "journal_entries"."kind"
+                         WHEN 'transfer' THEN CASE WHEN "entries"."amount" < 0 THEN 'transfer_debit' ELSE 'transfer_credit' END
+                         ELSE "journal_entries"."kind"::text END)::entry_type
+FROM "journal_entries"
+WHERE "journal_entries"."id" = "entries"."journal_entry_id";
+
+-- older transfers wrote their entries in the same transaction, so they share the transfer's created_at
+UPDATE "entries"
+SET "transfer_id" = "transfers"."id",
+    "entry_type"  = 'transfer_debit'
+FROM "transfers"
+WHERE "entries"."entry_type" IS NULL
+  AND "entries"."account_id" = "transfers"."from_account_id"
+  AND "entries"."amount" = -"transfers"."amount"
+  AND "entries"."created_at" = "transfers"."created_at";
+
+UPDATE "entries"
+SET "transfer_id" = "transfers"."id",
+    "entry_type"  = 'transfer_credit'
+FROM "transfers"
+WHERE "entries"."entry_type" IS NULL
+  AND "entries"."account_id" = "transfers"."to_account_id"
+  AND "entries"."amount" = "transfers"."to_amount"
+  AND "entries"."created_at" = "transfers"."created_at";
+
+-- the rest were deposits, withdrawals or adjustments, which can't be told apart anymore
+UPDATE "entries"
+SET "entry_type" = CASE WHEN "amount" < 0 THEN 'withdrawal'::entry_type ELSE 'deposit'::entry_type END
+WHERE "entry_type" IS NULL;
+
+ALTER TABLE "entries"
+    ALTER COLUMN "entry_type" SET NOT NULL;
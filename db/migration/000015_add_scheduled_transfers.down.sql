@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "scheduled_transfer_runs";
+
+DROP TYPE IF EXISTS "scheduled_run_status";
+
+DROP TABLE IF EXISTS "scheduled_transfers";
+
+DROP TYPE IF EXISTS "scheduled_transfer_status";
+
+DROP TYPE IF EXISTS "recurrence";
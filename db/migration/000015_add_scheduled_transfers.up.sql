@@ -0,0 +1,52 @@
+CREATE TYPE "recurrence" AS ENUM (
+    'once',
+    'daily',
+    'weekly',
+    'monthly'
+);
+
+CREATE TYPE "scheduled_transfer_status" AS ENUM (
+    'active',
+    'completed',
+    'cancelled'
+);
+
+CREATE TABLE "scheduled_transfers"
+(
+    "id"              bigserial PRIMARY KEY,
+    "owner"           varchar                   NOT NULL REFERENCES "users" ("username"),
+    "from_account_id" bigint                    NOT NULL REFERENCES "accounts" ("id"),
+    "to_account_id"   bigint                    NOT NULL REFERENCES "accounts" ("id"),
+    "amount"          bigint                    NOT NULL,
+    "recurrence"      recurrence                NOT NULL,
+    "start_at"        timestamptz               NOT NULL,
+    "next_run_at"     timestamptz               NOT NULL,
+    "last_run_at"     timestamptz,
+    "status"          scheduled_transfer_status NOT NULL DEFAULT 'active',
+    "created_at"      timestamptz               NOT NULL DEFAULT (now()),
+    CONSTRAINT "scheduled_amount_positive" CHECK ("amount" > 0)
+);
+
+COMMENT ON COLUMN "scheduled_transfers"."start_at" IS 'first run, later runs keep its time of day and day of month';
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+
+CREATE INDEX ON "scheduled_transfers" ("next_run_at") WHERE "status" = 'active';
+
+CREATE TYPE "scheduled_run_status" AS ENUM (
+    'succeeded',
+    'failed'
+);
+
+CREATE TABLE "scheduled_transfer_runs"
+(
+    "id"                    bigserial PRIMARY KEY,
+    "scheduled_transfer_id" bigint               NOT NULL REFERENCES "scheduled_transfers" ("id"),
+    "transfer_id"           bigint REFERENCES "transfers" ("id"),
+    "status"                scheduled_run_status NOT NULL,
+    "error"                 varchar              NOT NULL DEFAULT '',
+    "scheduled_for"         timestamptz          NOT NULL,
+    "created_at"            timestamptz          NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id");
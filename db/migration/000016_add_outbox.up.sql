@@ -0,0 +1,15 @@
+CREATE TABLE "outbox"
+(
+    "id"           bigserial PRIMARY KEY,
+    "event_type"   varchar     NOT NULL,
+    "aggregate_id" varchar     NOT NULL,
+    "payload"      jsonb       NOT NULL,
+    "attempts"     int         NOT NULL DEFAULT 0,
+    "last_error"   varchar     NOT NULL DEFAULT '',
+    "created_at"   timestamptz NOT NULL DEFAULT (now()),
+    "sent_at"      timestamptz
+);
+
+COMMENT ON COLUMN "outbox"."aggregate_id" IS 'username, account or transfer id the event is about';
+
+CREATE INDEX ON "outbox" ("id") WHERE "sent_at" IS NULL;
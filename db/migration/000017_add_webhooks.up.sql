@@ -0,0 +1,42 @@
+CREATE TABLE "webhooks"
+(
+    "id"         bigserial PRIMARY KEY,
+    "owner"      varchar     NOT NULL REFERENCES "users" ("username"),
+    "account_id" bigint      NOT NULL REFERENCES "accounts" ("id"),
+    "url"        varchar     NOT NULL,
+    "secret"     varchar     NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "webhooks"."secret" IS 'key of the HMAC-SHA256 signature of every delivery';
+
+CREATE INDEX ON "webhooks" ("owner");
+
+CREATE INDEX ON "webhooks" ("account_id");
+
+CREATE TYPE "webhook_delivery_status" AS ENUM (
+    'pending',
+    'succeeded',
+    'failed'
+);
+
+CREATE TABLE "webhook_deliveries"
+(
+    "id"              bigserial PRIMARY KEY,
+    "webhook_id"      bigint                  NOT NULL REFERENCES "webhooks" ("id") ON DELETE CASCADE,
+    "event_type"      varchar                 NOT NULL,
+    "payload"         jsonb                   NOT NULL,
+    "status"          webhook_delivery_status NOT NULL DEFAULT 'pending',
+    "attempts"        int                     NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz             NOT NULL DEFAULT (now()),
+    "response_status" int                     NOT NULL DEFAULT 0,
+    "last_error"      varchar                 NOT NULL DEFAULT '',
+    "created_at"      timestamptz             NOT NULL DEFAULT (now()),
+    "delivered_at"    timestamptz
+);
+
+COMMENT ON COLUMN "webhook_deliveries"."response_status" IS 'HTTP status of the last attempt, 0 when no response was received';
+
+CREATE INDEX ON "webhook_deliveries" ("webhook_id");
+
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
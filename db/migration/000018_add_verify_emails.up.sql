@@ -0,0 +1,18 @@
+ALTER TABLE "users"
+    ADD COLUMN "is_email_verified" bool NOT NULL DEFAULT false;
+
+-- users signed up before verification existed have no way to verify, so they keep using their accounts
+UPDATE "users" SET "is_email_verified" = true;
+
+CREATE TABLE "verify_emails"
+(
+    "id"          bigserial PRIMARY KEY,
+    "username"    varchar     NOT NULL REFERENCES "users" ("username"),
+    "email"       varchar     NOT NULL,
+    "secret_code" varchar     NOT NULL,
+    "is_used"     bool        NOT NULL DEFAULT false,
+    "created_at"  timestamptz NOT NULL DEFAULT (now()),
+    "expired_at"  timestamptz NOT NULL DEFAULT (now() + interval '15 minutes')
+);
+
+CREATE INDEX ON "verify_emails" ("username");
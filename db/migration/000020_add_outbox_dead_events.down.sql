@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "outbox_id_idx";
+
+CREATE INDEX ON "outbox" ("id") WHERE "sent_at" IS NULL;
+
+ALTER TABLE IF EXISTS "outbox" DROP COLUMN IF EXISTS "dead_at";
+
+ALTER TABLE IF EXISTS "outbox" DROP COLUMN IF EXISTS "next_attempt_at";
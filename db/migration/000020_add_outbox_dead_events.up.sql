@@ -0,0 +1,13 @@
+ALTER TABLE "outbox"
+    ADD COLUMN "next_attempt_at" timestamptz NOT NULL DEFAULT (now());
+
+ALTER TABLE "outbox"
+    ADD COLUMN "dead_at" timestamptz;
+
+COMMENT ON COLUMN "outbox"."next_attempt_at" IS 'claimed events are leased to their dispatcher until then';
+
+COMMENT ON COLUMN "outbox"."dead_at" IS 'set once the event failed the maximum number of attempts, dead events are no longer published';
+
+DROP INDEX IF EXISTS "outbox_id_idx";
+
+CREATE INDEX ON "outbox" ("id") WHERE "sent_at" IS NULL AND "dead_at" IS NULL;
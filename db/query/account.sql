@@ -15,11 +15,33 @@ select * from accounts where id = $1 limit 1 for no key update;
 -- name: ListAccounts :many
 select * from accounts where owner = $1 order by id limit $2 offset $3;
 
+-- name: ListAllAccounts :many
+select * from accounts order by id limit $1 offset $2;
+
 -- name: UpdateAccount :one
 update accounts set balance = $2 where id = $1 returning *;
 
 -- name: AddAccountBalance :one
 update accounts set balance = balance + sqlc.arg(amount) where id = sqlc.arg(id) returning *;
 
+-- name: UpdateAccountStatus :one
+update accounts set status = sqlc.arg(status) where id = sqlc.arg(id) returning *;
+
 -- name: DeleteAccount :exec
-delete from accounts where id = $1;
\ No newline at end of file
+delete from accounts where id = $1;
+
+-- name: UpdateAccountOverdraftLimit :one
+update accounts set overdraft_limit = sqlc.arg(overdraft_limit) where id = sqlc.arg(id) returning *;
+
+-- name: GetSystemAccount :one
+select * from accounts
+where owner = '_bank' and kind = sqlc.arg(kind) and currency = sqlc.arg(currency) and status <> 'closed'
+limit 1;
+
+-- name: CreateSystemAccount :exec
+INSERT INTO accounts (
+    owner, balance, currency, kind
+) VALUES (
+             '_bank', 0, sqlc.arg(currency), sqlc.arg(kind)
+         )
+ON CONFLICT (owner, currency, kind) WHERE status <> 'closed' DO NOTHING;
@@ -0,0 +1,16 @@
+-- name: CreateCurrency :one
+INSERT INTO currencies (
+    code, exponent, enabled
+) VALUES (
+             $1, $2, $3
+         )
+RETURNING *;
+
+-- name: GetCurrency :one
+select * from currencies where code = $1 limit 1;
+
+-- name: ListCurrencies :many
+select * from currencies order by code;
+
+-- name: UpdateCurrencyEnabled :one
+update currencies set enabled = sqlc.arg(enabled) where code = sqlc.arg(code) returning *;
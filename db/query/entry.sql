@@ -1,8 +1,8 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
-    account_id, amount
+    account_id, amount, journal_entry_id, transfer_id, entry_type
 ) VALUES (
-             $1, $2
+             $1, $2, $3, $4, $5
          )
 RETURNING *;
 
@@ -11,3 +11,17 @@ select * from entries where id = $1 limit 1;
 
 -- name: ListEntries :many
 select * from entries where account_id = $1 order by id limit $2 offset $3;
+
+-- name: ListEntriesAfter :many
+-- the counterparty is the other account of the transfer that produced the entry, 0 for other entries
+select sqlc.embed(entries),
+       coalesce(case when entries.account_id = transfers.from_account_id
+           then transfers.to_account_id else transfers.from_account_id end, 0)::bigint as counterparty_account_id
+from entries
+    left join transfers on transfers.id = entries.transfer_id
+where entries.account_id = sqlc.arg(account_id)
+  and entries.id > sqlc.arg(after_id)
+  and (sqlc.narg(from_time)::timestamptz is null or entries.created_at >= sqlc.narg(from_time))
+  and (sqlc.narg(to_time)::timestamptz is null or entries.created_at < sqlc.narg(to_time))
+order by entries.id
+limit sqlc.arg(page_size);
@@ -0,0 +1,10 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+    username, key, request_hash, response
+) VALUES (
+             $1, $2, $3, $4
+         )
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+select * from idempotency_keys where username = $1 and key = $2 limit 1;
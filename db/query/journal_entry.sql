@@ -0,0 +1,13 @@
+-- name: CreateJournalEntry :one
+INSERT INTO journal_entries (
+    kind, transfer_id
+) VALUES (
+             $1, $2
+         )
+RETURNING *;
+
+-- name: GetJournalEntry :one
+select * from journal_entries where id = $1 limit 1;
+
+-- name: ListPostings :many
+select * from entries where journal_entry_id = sqlc.arg(journal_entry_id)::bigint order by id;
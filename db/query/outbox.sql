@@ -0,0 +1,35 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+    event_type, aggregate_id, payload
+) VALUES (
+             $1, $2, $3
+         )
+RETURNING *;
+
+-- name: ListDueOutboxEventsForUpdate :many
+-- the oldest events that are neither sent, dead nor leased to another dispatcher
+select * from outbox
+where sent_at is null and dead_at is null and next_attempt_at <= sqlc.arg(now)
+order by id
+limit sqlc.arg(batch_size)
+for update skip locked;
+
+-- name: LeaseOutboxEvents :exec
+-- moves the next attempt of claimed events to the end of their lease, so no other dispatcher claims them meanwhile
+update outbox
+set next_attempt_at = sqlc.arg(next_attempt_at)
+where id = any(sqlc.arg(ids)::bigint[]);
+
+-- name: MarkOutboxEventSent :exec
+update outbox
+set sent_at = now(), attempts = attempts + 1, last_error = ''
+where id = $1;
+
+-- name: RecordOutboxEventFailure :one
+update outbox
+set attempts        = attempts + 1,
+    last_error      = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    dead_at         = case when attempts + 1 >= sqlc.arg(max_attempts)::int then now() end
+where id = sqlc.arg(id)
+returning *;
@@ -0,0 +1,17 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+    username, secret_code
+) VALUES (
+             $1, $2
+         )
+RETURNING *;
+
+-- name: UsePasswordReset :one
+-- marks the code used unless it is wrong, already used or expired
+update password_resets
+set is_used = true
+where id = sqlc.arg(id)
+  and secret_code = sqlc.arg(secret_code)
+  and is_used = false
+  and expired_at > now()
+returning *;
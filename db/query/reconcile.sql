@@ -0,0 +1,26 @@
+-- name: ListAccountEntryTotals :many
+select accounts.id, accounts.kind, accounts.currency, accounts.balance,
+       coalesce(sum(entries.amount), 0)::bigint as entries_total
+from accounts
+    left join entries on entries.account_id = accounts.id
+where accounts.id > sqlc.arg(after_id)
+group by accounts.id
+order by accounts.id
+limit sqlc.arg(batch_size);
+
+-- name: GetAccountEntriesTotal :one
+select coalesce(sum(amount), 0)::bigint as entries_total from entries where account_id = $1;
+
+-- name: ListTransferEntryCounts :many
+-- an entry matches when it is the transfer's debit of the source or its credit of the destination
+select transfers.id, transfers.from_account_id, transfers.to_account_id, transfers.amount, transfers.to_amount,
+       count(entries.id) filter (where (entries.entry_type = 'transfer_debit' and entries.account_id = transfers.from_account_id
+           and entries.amount = -transfers.amount)
+           or (entries.entry_type = 'transfer_credit' and entries.account_id = transfers.to_account_id
+               and entries.amount = transfers.to_amount))::int as matching_entries
+from transfers
+    left join entries on entries.transfer_id = transfers.id
+where transfers.id > sqlc.arg(after_id)
+group by transfers.id
+order by transfers.id
+limit sqlc.arg(batch_size);
@@ -0,0 +1,18 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (
+    id, username, expires_at
+) VALUES (
+             $1, $2, $3
+         )
+ON CONFLICT (id) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+-- a token is revoked as well when its user changed the password after it was issued
+select (exists(select 1 from revoked_tokens where id = sqlc.arg(id))
+    or exists(select 1
+              from users
+              where users.username = sqlc.arg(username)
+                and users.password_changed_at > sqlc.arg(issued_at)))::bool as revoked;
+
+-- name: DeleteExpiredRevokedTokens :execrows
+delete from revoked_tokens where expires_at < now();
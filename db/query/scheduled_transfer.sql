@@ -0,0 +1,61 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at
+) VALUES (
+             sqlc.arg(owner), sqlc.arg(from_account_id), sqlc.arg(to_account_id), sqlc.arg(amount),
+             sqlc.arg(recurrence), sqlc.arg(start_at), sqlc.arg(start_at)
+         )
+RETURNING *;
+
+-- name: GetScheduledTransfer :one
+select * from scheduled_transfers where id = $1 limit 1;
+
+-- name: ListScheduledTransfers :many
+select sqlc.embed(scheduled_transfers), accounts.currency
+from scheduled_transfers
+    join accounts on accounts.id = scheduled_transfers.from_account_id
+where scheduled_transfers.owner = $1
+order by scheduled_transfers.id
+limit $2 offset $3;
+
+-- name: UpdateScheduledTransfer :one
+update scheduled_transfers
+set amount      = sqlc.arg(amount),
+    recurrence  = sqlc.arg(recurrence),
+    start_at    = sqlc.arg(start_at),
+    next_run_at = sqlc.arg(start_at)
+where id = sqlc.arg(id) and status = 'active'
+returning *;
+
+-- name: CancelScheduledTransfer :one
+update scheduled_transfers set status = 'cancelled' where id = $1 and status = 'active' returning *;
+
+-- name: GetDueScheduledTransferForUpdate :one
+-- skipping the rows other workers hold lets several server instances share the work
+select * from scheduled_transfers
+where status = 'active' and next_run_at <= sqlc.arg(now)
+order by next_run_at
+limit 1
+for update skip locked;
+
+-- name: GetScheduledTransferForUpdate :one
+select * from scheduled_transfers where id = $1 limit 1 for no key update;
+
+-- name: AdvanceScheduledTransfer :one
+update scheduled_transfers
+set next_run_at = sqlc.arg(next_run_at),
+    last_run_at = sqlc.arg(last_run_at),
+    status      = sqlc.arg(status)
+where id = sqlc.arg(id)
+returning *;
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+    scheduled_transfer_id, transfer_id, status, error, scheduled_for
+) VALUES (
+             $1, $2, $3, $4, $5
+         )
+RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+select * from scheduled_transfer_runs where scheduled_transfer_id = $1 order by id desc limit $2 offset $3;
@@ -0,0 +1,21 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+    id, username, refresh_token, user_agent, client_ip, is_blocked, expires_at
+) VALUES (
+             $1, $2, $3, $4, $5, $6, $7
+         )
+RETURNING *;
+
+-- name: GetSession :one
+select * from sessions where id = $1 limit 1;
+
+-- name: ListActiveSessions :many
+select * from sessions
+where username = $1 and is_blocked = false and expires_at > now()
+order by created_at desc;
+
+-- name: BlockSession :one
+update sessions set is_blocked = true where id = $1 returning *;
+
+-- name: BlockUserSessions :execrows
+update sessions set is_blocked = true where username = $1 and is_blocked = false;
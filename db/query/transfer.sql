@@ -1,8 +1,8 @@
 -- name: CreateTransfer :one
 INSERT INTO transfers (
-    from_account_id, to_account_id, amount
+    from_account_id, to_account_id, amount, to_amount, exchange_rate
 ) VALUES (
-             $1, $2, $3
+             $1, $2, $3, $4, $5
          )
 RETURNING *;
 
@@ -11,3 +11,21 @@ select * from transfers where id = $1 limit 1;
 
 -- name: ListTransfers :many
 select * from transfers where from_account_id = $1 or to_account_id = $2 order by id limit $3 offset $4;
+
+-- name: ListAccountTransfers :many
+select sqlc.embed(transfers), fa.currency as from_currency, ta.currency as to_currency
+from transfers
+    join accounts fa on fa.id = transfers.from_account_id
+    join accounts ta on ta.id = transfers.to_account_id
+where ((sqlc.arg(outgoing)::bool and transfers.from_account_id = sqlc.arg(account_id))
+    or (sqlc.arg(incoming)::bool and transfers.to_account_id = sqlc.arg(account_id)))
+  and transfers.id > sqlc.arg(after_id)
+  and (sqlc.narg(from_time)::timestamptz is null or transfers.created_at >= sqlc.narg(from_time))
+  and (sqlc.narg(to_time)::timestamptz is null or transfers.created_at < sqlc.narg(to_time))
+  -- amounts are compared in the currency of the account: what it sent or what it received
+  and (sqlc.narg(min_amount)::bigint is null or (case when transfers.from_account_id = sqlc.arg(account_id)
+      then transfers.amount else transfers.to_amount end) >= sqlc.narg(min_amount))
+  and (sqlc.narg(max_amount)::bigint is null or (case when transfers.from_account_id = sqlc.arg(account_id)
+      then transfers.amount else transfers.to_amount end) <= sqlc.narg(max_amount))
+order by transfers.id
+limit sqlc.arg(page_size);
@@ -7,4 +7,25 @@ INSERT INTO users (
 RETURNING *;
 
 -- name: GetUser :one
-select * from users where username = $1 limit 1;
\ No newline at end of file
+select * from users where username = $1 limit 1;
+
+-- name: VerifyUserEmail :one
+-- the email must still be the one the code was sent to
+update users
+set is_email_verified = true
+where username = sqlc.arg(username) and email = sqlc.arg(email)
+returning *;
+
+-- name: GetUserByEmail :one
+select * from users where email = $1 limit 1;
+
+-- name: UpdateUserPassword :one
+-- tokens issued before password_changed_at are rejected, so the change signs the user out everywhere
+update users
+set hashed_password = sqlc.arg(hashed_password),
+    password_changed_at = sqlc.arg(password_changed_at)
+where username = sqlc.arg(username)
+returning *;
+
+-- name: GetUserPasswordChangedAt :one
+select password_changed_at from users where username = $1 limit 1;
@@ -0,0 +1,60 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+    owner, account_id, url, secret
+) VALUES (
+             $1, $2, $3, $4
+         )
+RETURNING *;
+
+-- name: GetWebhook :one
+select * from webhooks where id = $1 limit 1;
+
+-- name: ListWebhooks :many
+select * from webhooks
+where owner = $1
+order by id
+limit $2 offset $3;
+
+-- name: DeleteWebhook :exec
+delete from webhooks where id = $1;
+
+-- name: CreateWebhookDeliveries :execrows
+-- queues the event for every webhook of the account
+INSERT INTO webhook_deliveries (
+    webhook_id, event_type, payload
+)
+select id, sqlc.arg(event_type)::varchar, sqlc.arg(payload)::jsonb
+from webhooks
+where account_id = sqlc.arg(account_id);
+
+-- name: ListWebhookDeliveries :many
+select * from webhook_deliveries
+where webhook_id = $1
+order by id desc
+limit $2 offset $3;
+
+-- name: ListDueWebhookDeliveriesForUpdate :many
+select sqlc.embed(webhook_deliveries), webhooks.url, webhooks.secret
+from webhook_deliveries
+    join webhooks on webhooks.id = webhook_deliveries.webhook_id
+where webhook_deliveries.status = 'pending' and webhook_deliveries.next_attempt_at <= sqlc.arg(now)
+order by webhook_deliveries.next_attempt_at
+limit sqlc.arg(batch_size)
+for update of webhook_deliveries skip locked;
+
+-- name: LeaseWebhookDeliveries :exec
+-- moves the next attempt of claimed deliveries to the end of their lease, so no other worker claims them meanwhile
+update webhook_deliveries
+set next_attempt_at = sqlc.arg(lease_until)
+where id = any(sqlc.arg(ids)::bigint[]);
+
+-- name: RecordWebhookDeliveryAttempt :one
+update webhook_deliveries
+set status          = sqlc.arg(status),
+    attempts        = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    response_status = sqlc.arg(response_status),
+    last_error      = sqlc.arg(last_error),
+    delivered_at    = sqlc.narg(delivered_at)
+where id = sqlc.arg(id)
+returning *;
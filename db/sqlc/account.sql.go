@@ -10,7 +10,7 @@ import (
 )
 
 const addAccountBalance = `-- name: AddAccountBalance :one
-update accounts set balance = balance + $1 where id = $2 returning id, owner, balance, currency, created_at
+update accounts set balance = balance + $1 where id = $2 returning id, owner, balance, currency, created_at, overdraft_limit, status, kind
 `
 
 type AddAccountBalanceParams struct {
@@ -27,6 +27,9 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.Kind,
 	)
 	return i, err
 }
@@ -37,7 +40,7 @@ INSERT INTO accounts (
 ) VALUES (
              $1, $2, $3
          )
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, status, kind
 `
 
 type CreateAccountParams struct {
@@ -55,10 +58,32 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.Kind,
 	)
 	return i, err
 }
 
+const createSystemAccount = `-- name: CreateSystemAccount :exec
+INSERT INTO accounts (
+    owner, balance, currency, kind
+) VALUES (
+             '_bank', 0, $1, $2
+         )
+ON CONFLICT (owner, currency, kind) WHERE status <> 'closed' DO NOTHING
+`
+
+type CreateSystemAccountParams struct {
+	Currency string      `json:"currency"`
+	Kind     AccountKind `json:"kind"`
+}
+
+func (q *Queries) CreateSystemAccount(ctx context.Context, arg CreateSystemAccountParams) error {
+	_, err := q.db.ExecContext(ctx, createSystemAccount, arg.Currency, arg.Kind)
+	return err
+}
+
 const deleteAccount = `-- name: DeleteAccount :exec
 delete from accounts where id = $1
 `
@@ -69,7 +94,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-select id, owner, balance, currency, created_at from accounts where id = $1 limit 1
+select id, owner, balance, currency, created_at, overdraft_limit, status, kind from accounts where id = $1 limit 1
 `
 
 func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
@@ -81,12 +106,15 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.Kind,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-select id, owner, balance, currency, created_at from accounts where id = $1 limit 1 for no key update
+select id, owner, balance, currency, created_at, overdraft_limit, status, kind from accounts where id = $1 limit 1 for no key update
 `
 
 func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
@@ -98,12 +126,42 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.Kind,
+	)
+	return i, err
+}
+
+const getSystemAccount = `-- name: GetSystemAccount :one
+select id, owner, balance, currency, created_at, overdraft_limit, status, kind from accounts
+where owner = '_bank' and kind = $1 and currency = $2 and status <> 'closed'
+limit 1
+`
+
+type GetSystemAccountParams struct {
+	Kind     AccountKind `json:"kind"`
+	Currency string      `json:"currency"`
+}
+
+func (q *Queries) GetSystemAccount(ctx context.Context, arg GetSystemAccountParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getSystemAccount, arg.Kind, arg.Currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.Kind,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-select id, owner, balance, currency, created_at from accounts where owner = $1 order by id limit $2 offset $3
+select id, owner, balance, currency, created_at, overdraft_limit, status, kind from accounts where owner = $1 order by id limit $2 offset $3
 `
 
 type ListAccountsParams struct {
@@ -127,6 +185,50 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.Status,
+			&i.Kind,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllAccounts = `-- name: ListAllAccounts :many
+select id, owner, balance, currency, created_at, overdraft_limit, status, kind from accounts order by id limit $1 offset $2
+`
+
+type ListAllAccountsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAllAccounts, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.Status,
+			&i.Kind,
 		); err != nil {
 			return nil, err
 		}
@@ -142,7 +244,7 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 }
 
 const updateAccount = `-- name: UpdateAccount :one
-update accounts set balance = $2 where id = $1 returning id, owner, balance, currency, created_at
+update accounts set balance = $2 where id = $1 returning id, owner, balance, currency, created_at, overdraft_limit, status, kind
 `
 
 type UpdateAccountParams struct {
@@ -159,6 +261,59 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.Kind,
+	)
+	return i, err
+}
+
+const updateAccountOverdraftLimit = `-- name: UpdateAccountOverdraftLimit :one
+update accounts set overdraft_limit = $1 where id = $2 returning id, owner, balance, currency, created_at, overdraft_limit, status, kind
+`
+
+type UpdateAccountOverdraftLimitParams struct {
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	ID             int64 `json:"id"`
+}
+
+func (q *Queries) UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountOverdraftLimit, arg.OverdraftLimit, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.Kind,
+	)
+	return i, err
+}
+
+const updateAccountStatus = `-- name: UpdateAccountStatus :one
+update accounts set status = $1 where id = $2 returning id, owner, balance, currency, created_at, overdraft_limit, status, kind
+`
+
+type UpdateAccountStatusParams struct {
+	Status AccountStatus `json:"status"`
+	ID     int64         `json:"id"`
+}
+
+func (q *Queries) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountStatus, arg.Status, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.Kind,
 	)
 	return i, err
 }
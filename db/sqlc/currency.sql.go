@@ -0,0 +1,106 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: currency.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createCurrency = `-- name: CreateCurrency :one
+INSERT INTO currencies (
+    code, exponent, enabled
+) VALUES (
+             $1, $2, $3
+         )
+RETURNING code, exponent, enabled, created_at
+`
+
+type CreateCurrencyParams struct {
+	Code     string `json:"code"`
+	Exponent int16  `json:"exponent"`
+	Enabled  bool   `json:"enabled"`
+}
+
+func (q *Queries) CreateCurrency(ctx context.Context, arg CreateCurrencyParams) (Currency, error) {
+	row := q.db.QueryRowContext(ctx, createCurrency, arg.Code, arg.Exponent, arg.Enabled)
+	var i Currency
+	err := row.Scan(
+		&i.Code,
+		&i.Exponent,
+		&i.Enabled,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getCurrency = `-- name: GetCurrency :one
+select code, exponent, enabled, created_at from currencies where code = $1 limit 1
+`
+
+func (q *Queries) GetCurrency(ctx context.Context, code string) (Currency, error) {
+	row := q.db.QueryRowContext(ctx, getCurrency, code)
+	var i Currency
+	err := row.Scan(
+		&i.Code,
+		&i.Exponent,
+		&i.Enabled,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listCurrencies = `-- name: ListCurrencies :many
+select code, exponent, enabled, created_at from currencies order by code
+`
+
+func (q *Queries) ListCurrencies(ctx context.Context) ([]Currency, error) {
+	rows, err := q.db.QueryContext(ctx, listCurrencies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Currency{}
+	for rows.Next() {
+		var i Currency
+		if err := rows.Scan(
+			&i.Code,
+			&i.Exponent,
+			&i.Enabled,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateCurrencyEnabled = `-- name: UpdateCurrencyEnabled :one
+update currencies set enabled = $1 where code = $2 returning code, exponent, enabled, created_at
+`
+
+type UpdateCurrencyEnabledParams struct {
+	Enabled bool   `json:"enabled"`
+	Code    string `json:"code"`
+}
+
+func (q *Queries) UpdateCurrencyEnabled(ctx context.Context, arg UpdateCurrencyEnabledParams) (Currency, error) {
+	row := q.db.QueryRowContext(ctx, updateCurrencyEnabled, arg.Enabled, arg.Code)
+	var i Currency
+	err := row.Scan(
+		&i.Code,
+		&i.Exponent,
+		&i.Enabled,
+		&i.CreatedAt,
+	)
+	return i, err
+}
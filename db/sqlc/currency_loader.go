@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"github.com/vadym-98/simple_bank/util"
+)
+
+// LoadCurrencies adapts the currencies table to the currency registry
+func LoadCurrencies(queries Querier) util.CurrencyLoader {
+	return func(ctx context.Context) ([]util.Currency, error) {
+		rows, err := queries.ListCurrencies(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		currencies := make([]util.Currency, len(rows))
+		for i, row := range rows {
+			currencies[i] = util.Currency{
+				Code:     row.Code,
+				Exponent: row.Exponent,
+				Enabled:  row.Enabled,
+			}
+		}
+
+		return currencies, nil
+	}
+}
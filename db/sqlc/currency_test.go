@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+)
+
+func TestListCurrencies(t *testing.T) {
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+
+	codes := make([]string, len(currencies))
+	for i, currency := range currencies {
+		codes[i] = currency.Code
+	}
+	require.Subset(t, codes, []string{util.CAD, util.EUR, util.USD})
+}
+
+func TestUpdateCurrencyEnabled(t *testing.T) {
+	code := util.RandomString(3)
+	currency, err := testQueries.CreateCurrency(context.Background(), CreateCurrencyParams{
+		Code:     code,
+		Exponent: 0,
+		Enabled:  false,
+	})
+	require.NoError(t, err)
+	require.False(t, currency.Enabled)
+
+	currency, err = testQueries.UpdateCurrencyEnabled(context.Background(), UpdateCurrencyEnabledParams{
+		Enabled: true,
+		Code:    code,
+	})
+	require.NoError(t, err)
+	require.True(t, currency.Enabled)
+
+	currency2, err := testQueries.GetCurrency(context.Background(), code)
+	require.NoError(t, err)
+	require.Equal(t, currency, currency2)
+
+	_, err = testQueries.GetCurrency(context.Background(), "ZZZZ")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateAccountUnknownCurrency(t *testing.T) {
+	user := createRandomUser(t)
+
+	_, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: "ZZZ",
+	})
+	require.Error(t, err)
+}
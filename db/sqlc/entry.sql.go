@@ -7,36 +7,49 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
-    account_id, amount
+    account_id, amount, journal_entry_id, transfer_id, entry_type
 ) VALUES (
-             $1, $2
+             $1, $2, $3, $4, $5
          )
-RETURNING id, account_id, amount, created_at
+RETURNING id, account_id, amount, created_at, journal_entry_id, transfer_id, entry_type
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID      int64         `json:"account_id"`
+	Amount         int64         `json:"amount"`
+	JournalEntryID sql.NullInt64 `json:"journal_entry_id"`
+	TransferID     sql.NullInt64 `json:"transfer_id"`
+	EntryType      EntryType     `json:"entry_type"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.JournalEntryID,
+		arg.TransferID,
+		arg.EntryType,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.JournalEntryID,
+		&i.TransferID,
+		&i.EntryType,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-select id, account_id, amount, created_at from entries where id = $1 limit 1
+select id, account_id, amount, created_at, journal_entry_id, transfer_id, entry_type from entries where id = $1 limit 1
 `
 
 func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
@@ -47,12 +60,15 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.JournalEntryID,
+		&i.TransferID,
+		&i.EntryType,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-select id, account_id, amount, created_at from entries where account_id = $1 order by id limit $2 offset $3
+select id, account_id, amount, created_at, journal_entry_id, transfer_id, entry_type from entries where account_id = $1 order by id limit $2 offset $3
 `
 
 type ListEntriesParams struct {
@@ -75,6 +91,75 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.JournalEntryID,
+			&i.TransferID,
+			&i.EntryType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesAfter = `-- name: ListEntriesAfter :many
+select entries.id, entries.account_id, entries.amount, entries.created_at, entries.journal_entry_id, entries.transfer_id, entries.entry_type,
+       coalesce(case when entries.account_id = transfers.from_account_id
+           then transfers.to_account_id else transfers.from_account_id end, 0)::bigint as counterparty_account_id
+from entries
+    left join transfers on transfers.id = entries.transfer_id
+where entries.account_id = $1
+  and entries.id > $2
+  and ($3::timestamptz is null or entries.created_at >= $3)
+  and ($4::timestamptz is null or entries.created_at < $4)
+order by entries.id
+limit $5
+`
+
+type ListEntriesAfterParams struct {
+	AccountID int64        `json:"account_id"`
+	AfterID   int64        `json:"after_id"`
+	FromTime  sql.NullTime `json:"from_time"`
+	ToTime    sql.NullTime `json:"to_time"`
+	PageSize  int32        `json:"page_size"`
+}
+
+type ListEntriesAfterRow struct {
+	Entry                 Entry `json:"entry"`
+	CounterpartyAccountID int64 `json:"counterparty_account_id"`
+}
+
+// the counterparty is the other account of the transfer that produced the entry, 0 for other entries
+func (q *Queries) ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]ListEntriesAfterRow, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesAfter,
+		arg.AccountID,
+		arg.AfterID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListEntriesAfterRow{}
+	for rows.Next() {
+		var i ListEntriesAfterRow
+		if err := rows.Scan(
+			&i.Entry.ID,
+			&i.Entry.AccountID,
+			&i.Entry.Amount,
+			&i.Entry.CreatedAt,
+			&i.Entry.JournalEntryID,
+			&i.Entry.TransferID,
+			&i.Entry.EntryType,
+			&i.CounterpartyAccountID,
 		); err != nil {
 			return nil, err
 		}
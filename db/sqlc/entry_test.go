@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"github.com/stretchr/testify/require"
 	"github.com/vadym-98/simple_bank/util"
 	"testing"
@@ -14,6 +15,7 @@ func createRandomEntry(t *testing.T) Entry {
 	arg := CreateEntryParams{
 		AccountID: account1.ID,
 		Amount:    util.RandomMoney(),
+		EntryType: EntryTypeDeposit,
 	}
 
 	entry, err := testQueries.CreateEntry(context.Background(), arg)
@@ -47,8 +49,9 @@ func TestListEntries(t *testing.T) {
 	account1 := createRandomAccount(t)
 	for i := 0; i < 10; i++ {
 		arg := CreateEntryParams{
-			account1.ID,
-			util.RandomMoney(),
+			AccountID: account1.ID,
+			Amount:    util.RandomMoney(),
+			EntryType: EntryTypeDeposit,
 		}
 		e, err := testQueries.CreateEntry(context.Background(), arg)
 		require.NoError(t, err)
@@ -68,3 +71,83 @@ func TestListEntries(t *testing.T) {
 		require.NotEmpty(t, e)
 	}
 }
+
+func TestListEntriesAfter(t *testing.T) {
+	account1 := createRandomAccount(t)
+
+	var created []Entry
+	for i := 0; i < 10; i++ {
+		e, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account1.ID,
+			Amount:    util.RandomMoney(),
+			EntryType: EntryTypeDeposit,
+		})
+		require.NoError(t, err)
+		created = append(created, e)
+	}
+
+	arg := ListEntriesAfterParams{
+		AccountID: account1.ID,
+		AfterID:   created[4].ID,
+		PageSize:  3,
+	}
+	entries, err := testQueries.ListEntriesAfter(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	for i, e := range entries {
+		require.Equal(t, created[5+i].ID, e.Entry.ID)
+		require.Zero(t, e.CounterpartyAccountID)
+	}
+
+	// entries created after the upper bound are filtered out
+	arg = ListEntriesAfterParams{
+		AccountID: account1.ID,
+		ToTime:    sql.NullTime{Time: created[0].CreatedAt.Add(-time.Second), Valid: true},
+		PageSize:  10,
+	}
+	entries, err = testQueries.ListEntriesAfter(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestListEntriesAfterTransfer(t *testing.T) {
+	store := NewStore(testDB)
+
+	a1 := createAccountWithBalance(t, 100)
+	a2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, EntryTypeTransferDebit, result.FromEntry.EntryType)
+	require.Equal(t, EntryTypeTransferCredit, result.ToEntry.EntryType)
+	require.Equal(t, result.Transfer.ID, result.FromEntry.TransferID.Int64)
+	require.Equal(t, result.Transfer.ID, result.ToEntry.TransferID.Int64)
+
+	testCases := []struct {
+		account      Account
+		entryType    EntryType
+		counterparty int64
+	}{
+		{account: a1, entryType: EntryTypeTransferDebit, counterparty: a2.ID},
+		{account: a2, entryType: EntryTypeTransferCredit, counterparty: a1.ID},
+	}
+
+	for _, tc := range testCases {
+		entries, err := testQueries.ListEntriesAfter(context.Background(), ListEntriesAfterParams{
+			AccountID: tc.account.ID,
+			PageSize:  10,
+		})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		require.Equal(t, tc.entryType, entries[0].Entry.EntryType)
+		require.Equal(t, result.Transfer.ID, entries[0].Entry.TransferID.Int64)
+		require.Equal(t, tc.counterparty, entries[0].CounterpartyAccountID)
+	}
+}
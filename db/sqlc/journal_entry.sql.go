@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: journal_entry.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createJournalEntry = `-- name: CreateJournalEntry :one
+INSERT INTO journal_entries (
+    kind, transfer_id
+) VALUES (
+             $1, $2
+         )
+RETURNING id, kind, created_at, transfer_id
+`
+
+type CreateJournalEntryParams struct {
+	Kind       JournalKind   `json:"kind"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+}
+
+func (q *Queries) CreateJournalEntry(ctx context.Context, arg CreateJournalEntryParams) (JournalEntry, error) {
+	row := q.db.QueryRowContext(ctx, createJournalEntry, arg.Kind, arg.TransferID)
+	var i JournalEntry
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.CreatedAt,
+		&i.TransferID,
+	)
+	return i, err
+}
+
+const getJournalEntry = `-- name: GetJournalEntry :one
+select id, kind, created_at, transfer_id from journal_entries where id = $1 limit 1
+`
+
+func (q *Queries) GetJournalEntry(ctx context.Context, id int64) (JournalEntry, error) {
+	row := q.db.QueryRowContext(ctx, getJournalEntry, id)
+	var i JournalEntry
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.CreatedAt,
+		&i.TransferID,
+	)
+	return i, err
+}
+
+const listPostings = `-- name: ListPostings :many
+select id, account_id, amount, created_at, journal_entry_id, transfer_id, entry_type from entries where journal_entry_id = $1::bigint order by id
+`
+
+func (q *Queries) ListPostings(ctx context.Context, journalEntryID int64) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listPostings, journalEntryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.JournalEntryID,
+			&i.TransferID,
+			&i.EntryType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -5,32 +5,492 @@
 package db
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
+)
+
+type AccountKind string
+
+const (
+	AccountKindCustomer AccountKind = "customer"
+	AccountKindFees     AccountKind = "fees"
+	AccountKindSuspense AccountKind = "suspense"
+)
+
+func (e *AccountKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AccountKind(s)
+	case string:
+		*e = AccountKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AccountKind: %T", src)
+	}
+	return nil
+}
+
+type NullAccountKind struct {
+	AccountKind AccountKind `json:"account_kind"`
+	Valid       bool        `json:"valid"` // Valid is true if AccountKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAccountKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.AccountKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AccountKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAccountKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AccountKind), nil
+}
+
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "active"
+	AccountStatusFrozen AccountStatus = "frozen"
+	AccountStatusClosed AccountStatus = "closed"
+)
+
+func (e *AccountStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AccountStatus(s)
+	case string:
+		*e = AccountStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AccountStatus: %T", src)
+	}
+	return nil
+}
+
+type NullAccountStatus struct {
+	AccountStatus AccountStatus `json:"account_status"`
+	Valid         bool          `json:"valid"` // Valid is true if AccountStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAccountStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.AccountStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AccountStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAccountStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AccountStatus), nil
+}
+
+type EntryType string
+
+const (
+	EntryTypeTransferDebit  EntryType = "transfer_debit"
+	EntryTypeTransferCredit EntryType = "transfer_credit"
+	EntryTypeDeposit        EntryType = "deposit"
+	EntryTypeWithdrawal     EntryType = "withdrawal"
+	EntryTypeFee            EntryType = "fee"
+	EntryTypeInterest       EntryType = "interest"
+	EntryTypeAdjustment     EntryType = "adjustment"
+)
+
+func (e *EntryType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EntryType(s)
+	case string:
+		*e = EntryType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EntryType: %T", src)
+	}
+	return nil
+}
+
+type NullEntryType struct {
+	EntryType EntryType `json:"entry_type"`
+	Valid     bool      `json:"valid"` // Valid is true if EntryType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEntryType) Scan(value interface{}) error {
+	if value == nil {
+		ns.EntryType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EntryType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEntryType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EntryType), nil
+}
+
+type JournalKind string
+
+const (
+	JournalKindTransfer   JournalKind = "transfer"
+	JournalKindDeposit    JournalKind = "deposit"
+	JournalKindWithdrawal JournalKind = "withdrawal"
+	JournalKindAdjustment JournalKind = "adjustment"
+	JournalKindFee        JournalKind = "fee"
+)
+
+func (e *JournalKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = JournalKind(s)
+	case string:
+		*e = JournalKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for JournalKind: %T", src)
+	}
+	return nil
+}
+
+type NullJournalKind struct {
+	JournalKind JournalKind `json:"journal_kind"`
+	Valid       bool        `json:"valid"` // Valid is true if JournalKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullJournalKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.JournalKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.JournalKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullJournalKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.JournalKind), nil
+}
+
+type Recurrence string
+
+const (
+	RecurrenceOnce    Recurrence = "once"
+	RecurrenceDaily   Recurrence = "daily"
+	RecurrenceWeekly  Recurrence = "weekly"
+	RecurrenceMonthly Recurrence = "monthly"
+)
+
+func (e *Recurrence) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = Recurrence(s)
+	case string:
+		*e = Recurrence(s)
+	default:
+		return fmt.Errorf("unsupported scan type for Recurrence: %T", src)
+	}
+	return nil
+}
+
+type NullRecurrence struct {
+	Recurrence Recurrence `json:"recurrence"`
+	Valid      bool       `json:"valid"` // Valid is true if Recurrence is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRecurrence) Scan(value interface{}) error {
+	if value == nil {
+		ns.Recurrence, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.Recurrence.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRecurrence) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.Recurrence), nil
+}
+
+type ScheduledRunStatus string
+
+const (
+	ScheduledRunStatusSucceeded ScheduledRunStatus = "succeeded"
+	ScheduledRunStatusFailed    ScheduledRunStatus = "failed"
 )
 
+func (e *ScheduledRunStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ScheduledRunStatus(s)
+	case string:
+		*e = ScheduledRunStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ScheduledRunStatus: %T", src)
+	}
+	return nil
+}
+
+type NullScheduledRunStatus struct {
+	ScheduledRunStatus ScheduledRunStatus `json:"scheduled_run_status"`
+	Valid              bool               `json:"valid"` // Valid is true if ScheduledRunStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullScheduledRunStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ScheduledRunStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ScheduledRunStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullScheduledRunStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ScheduledRunStatus), nil
+}
+
+type ScheduledTransferStatus string
+
+const (
+	ScheduledTransferStatusActive    ScheduledTransferStatus = "active"
+	ScheduledTransferStatusCompleted ScheduledTransferStatus = "completed"
+	ScheduledTransferStatusCancelled ScheduledTransferStatus = "cancelled"
+)
+
+func (e *ScheduledTransferStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ScheduledTransferStatus(s)
+	case string:
+		*e = ScheduledTransferStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ScheduledTransferStatus: %T", src)
+	}
+	return nil
+}
+
+type NullScheduledTransferStatus struct {
+	ScheduledTransferStatus ScheduledTransferStatus `json:"scheduled_transfer_status"`
+	Valid                   bool                    `json:"valid"` // Valid is true if ScheduledTransferStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullScheduledTransferStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ScheduledTransferStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ScheduledTransferStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullScheduledTransferStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ScheduledTransferStatus), nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveryStatus struct {
+	WebhookDeliveryStatus WebhookDeliveryStatus `json:"webhook_delivery_status"`
+	Valid                 bool                  `json:"valid"` // Valid is true if WebhookDeliveryStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveryStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveryStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveryStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveryStatus), nil
+}
+
 type Account struct {
 	ID        int64     `json:"id"`
 	Owner     string    `json:"owner"`
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far below zero the balance may go
+	OverdraftLimit int64         `json:"overdraft_limit"`
+	Status         AccountStatus `json:"status"`
+	// system accounts hold the other side of fees, deposits, withdrawals and currency exchanges
+	Kind AccountKind `json:"kind"`
+}
+
+type Currency struct {
+	// ISO 4217 alphabetic code
+	Code string `json:"code"`
+	// number of minor unit digits, 2 for cents
+	Exponent  int16     `json:"exponent"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
 	ID        int64 `json:"id"`
 	AccountID int64 `json:"account_id"`
 	// can be negative or positive
-	Amount    int64     `json:"amount"`
-	CreatedAt time.Time `json:"created_at"`
+	Amount         int64         `json:"amount"`
+	CreatedAt      time.Time     `json:"created_at"`
+	JournalEntryID sql.NullInt64 `json:"journal_entry_id"`
+	TransferID     sql.NullInt64 `json:"transfer_id"`
+	EntryType      EntryType     `json:"entry_type"`
+}
+
+type IdempotencyKey struct {
+	Username    string          `json:"username"`
+	Key         string          `json:"key"`
+	RequestHash string          `json:"request_hash"`
+	Response    json.RawMessage `json:"response"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type JournalEntry struct {
+	ID         int64         `json:"id"`
+	Kind       JournalKind   `json:"kind"`
+	CreatedAt  time.Time     `json:"created_at"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+}
+
+type Outbox struct {
+	ID        int64  `json:"id"`
+	EventType string `json:"event_type"`
+	// username, account or transfer id the event is about
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int32           `json:"attempts"`
+	LastError   string          `json:"last_error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	SentAt      sql.NullTime    `json:"sent_at"`
+	// claimed events are leased to their dispatcher until then
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// set once the event failed the maximum number of attempts, dead events are no longer published
+	DeadAt sql.NullTime `json:"dead_at"`
+}
+
+type PasswordReset struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64      `json:"id"`
+	Owner         string     `json:"owner"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        int64      `json:"amount"`
+	Recurrence    Recurrence `json:"recurrence"`
+	// first run, later runs keep its time of day and day of month
+	StartAt   time.Time               `json:"start_at"`
+	NextRunAt time.Time               `json:"next_run_at"`
+	LastRunAt sql.NullTime            `json:"last_run_at"`
+	Status    ScheduledTransferStatus `json:"status"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64              `json:"id"`
+	ScheduledTransferID int64              `json:"scheduled_transfer_id"`
+	TransferID          sql.NullInt64      `json:"transfer_id"`
+	Status              ScheduledRunStatus `json:"status"`
+	Error               string             `json:"error"`
+	ScheduledFor        time.Time          `json:"scheduled_for"`
+	CreatedAt           time.Time          `json:"created_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	// must be positive
+	// must be positive, in the currency of the source account
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// credited to the destination account, in its currency
+	ToAmount int64 `json:"to_amount"`
+	// units of the destination currency per unit of the source currency
+	ExchangeRate float64 `json:"exchange_rate"`
 }
 
 type User struct {
@@ -40,4 +500,41 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+}
+
+type VerifyEmail struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+type Webhook struct {
+	ID        int64  `json:"id"`
+	Owner     string `json:"owner"`
+	AccountID int64  `json:"account_id"`
+	Url       string `json:"url"`
+	// key of the HMAC-SHA256 signature of every delivery
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID            int64                 `json:"id"`
+	WebhookID     int64                 `json:"webhook_id"`
+	EventType     string                `json:"event_type"`
+	Payload       json.RawMessage       `json:"payload"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int32                 `json:"attempts"`
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+	// HTTP status of the last attempt, 0 when no response was received
+	ResponseStatus int32        `json:"response_status"`
+	LastError      string       `json:"last_error"`
+	CreatedAt      time.Time    `json:"created_at"`
+	DeliveredAt    sql.NullTime `json:"delivered_at"`
 }
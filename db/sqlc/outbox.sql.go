@@ -0,0 +1,163 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: outbox.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+    event_type, aggregate_id, payload
+) VALUES (
+             $1, $2, $3
+         )
+RETURNING id, event_type, aggregate_id, payload, attempts, last_error, created_at, sent_at, next_attempt_at, dead_at
+`
+
+type CreateOutboxEventParams struct {
+	EventType   string          `json:"event_type"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error) {
+	row := q.db.QueryRowContext(ctx, createOutboxEvent, arg.EventType, arg.AggregateID, arg.Payload)
+	var i Outbox
+	err := row.Scan(
+		&i.ID,
+		&i.EventType,
+		&i.AggregateID,
+		&i.Payload,
+		&i.Attempts,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.SentAt,
+		&i.NextAttemptAt,
+		&i.DeadAt,
+	)
+	return i, err
+}
+
+const leaseOutboxEvents = `-- name: LeaseOutboxEvents :exec
+update outbox
+set next_attempt_at = $1
+where id = any($2::bigint[])
+`
+
+type LeaseOutboxEventsParams struct {
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	Ids           []int64   `json:"ids"`
+}
+
+// moves the next attempt of claimed events to the end of their lease, so no other dispatcher claims them meanwhile
+func (q *Queries) LeaseOutboxEvents(ctx context.Context, arg LeaseOutboxEventsParams) error {
+	_, err := q.db.ExecContext(ctx, leaseOutboxEvents, arg.NextAttemptAt, pq.Array(arg.Ids))
+	return err
+}
+
+const listDueOutboxEventsForUpdate = `-- name: ListDueOutboxEventsForUpdate :many
+select id, event_type, aggregate_id, payload, attempts, last_error, created_at, sent_at, next_attempt_at, dead_at from outbox
+where sent_at is null and dead_at is null and next_attempt_at <= $1
+order by id
+limit $2
+for update skip locked
+`
+
+type ListDueOutboxEventsForUpdateParams struct {
+	Now       time.Time `json:"now"`
+	BatchSize int32     `json:"batch_size"`
+}
+
+// the oldest events that are neither sent, dead nor leased to another dispatcher
+func (q *Queries) ListDueOutboxEventsForUpdate(ctx context.Context, arg ListDueOutboxEventsForUpdateParams) ([]Outbox, error) {
+	rows, err := q.db.QueryContext(ctx, listDueOutboxEventsForUpdate, arg.Now, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Outbox{}
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.AggregateID,
+			&i.Payload,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.SentAt,
+			&i.NextAttemptAt,
+			&i.DeadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxEventSent = `-- name: MarkOutboxEventSent :exec
+update outbox
+set sent_at = now(), attempts = attempts + 1, last_error = ''
+where id = $1
+`
+
+func (q *Queries) MarkOutboxEventSent(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEventSent, id)
+	return err
+}
+
+const recordOutboxEventFailure = `-- name: RecordOutboxEventFailure :one
+update outbox
+set attempts        = attempts + 1,
+    last_error      = $1,
+    next_attempt_at = $2,
+    dead_at         = case when attempts + 1 >= $3::int then now() end
+where id = $4
+returning id, event_type, aggregate_id, payload, attempts, last_error, created_at, sent_at, next_attempt_at, dead_at
+`
+
+type RecordOutboxEventFailureParams struct {
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	MaxAttempts   int32     `json:"max_attempts"`
+	ID            int64     `json:"id"`
+}
+
+func (q *Queries) RecordOutboxEventFailure(ctx context.Context, arg RecordOutboxEventFailureParams) (Outbox, error) {
+	row := q.db.QueryRowContext(ctx, recordOutboxEventFailure,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.MaxAttempts,
+		arg.ID,
+	)
+	var i Outbox
+	err := row.Scan(
+		&i.ID,
+		&i.EventType,
+		&i.AggregateID,
+		&i.Payload,
+		&i.Attempts,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.SentAt,
+		&i.NextAttemptAt,
+		&i.DeadAt,
+	)
+	return i, err
+}
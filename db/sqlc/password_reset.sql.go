@@ -0,0 +1,68 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: password_reset.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createPasswordReset = `-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+    username, secret_code
+) VALUES (
+             $1, $2
+         )
+RETURNING id, username, secret_code, is_used, created_at, expired_at
+`
+
+type CreatePasswordResetParams struct {
+	Username   string `json:"username"`
+	SecretCode string `json:"secret_code"`
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRowContext(ctx, createPasswordReset, arg.Username, arg.SecretCode)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.SecretCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const usePasswordReset = `-- name: UsePasswordReset :one
+update password_resets
+set is_used = true
+where id = $1
+  and secret_code = $2
+  and is_used = false
+  and expired_at > now()
+returning id, username, secret_code, is_used, created_at, expired_at
+`
+
+type UsePasswordResetParams struct {
+	ID         int64  `json:"id"`
+	SecretCode string `json:"secret_code"`
+}
+
+// marks the code used unless it is wrong, already used or expired
+func (q *Queries) UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRowContext(ctx, usePasswordReset, arg.ID, arg.SecretCode)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.SecretCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
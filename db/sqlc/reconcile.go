@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DefaultReconcileBatchSize is used when ReconcileParams doesn't set a batch size
+const DefaultReconcileBatchSize = 500
+
+// ReconcileParams contains the input parameters of the reconciliation
+type ReconcileParams struct {
+	// BatchSize is how many accounts or transfers are read per query
+	BatchSize int32 `json:"batch_size"`
+	// Fix writes an adjustment entry for every customer account whose entries don't add up to its balance
+	Fix bool `json:"fix"`
+	// TxOptions overrides the repeatable read, read-only isolation of the scan
+	TxOptions *sql.TxOptions `json:"-"`
+}
+
+// BalanceDiscrepancy is an account whose balance differs from the sum of its entries
+type BalanceDiscrepancy struct {
+	AccountID    int64       `json:"account_id"`
+	Kind         AccountKind `json:"kind"`
+	Currency     string      `json:"currency"`
+	Balance      int64       `json:"balance"`
+	EntriesTotal int64       `json:"entries_total"`
+	// Difference is what the entries are missing: Balance - EntriesTotal
+	Difference int64 `json:"difference"`
+	// JournalEntryID is the correcting adjustment, set only when it was written
+	JournalEntryID int64 `json:"journal_entry_id,omitempty"`
+}
+
+// TransferDiscrepancy is a transfer that isn't carried out by exactly one debit and one credit entry
+type TransferDiscrepancy struct {
+	TransferID      int64 `json:"transfer_id"`
+	FromAccountID   int64 `json:"from_account_id"`
+	ToAccountID     int64 `json:"to_account_id"`
+	Amount          int64 `json:"amount"`
+	ToAmount        int64 `json:"to_amount"`
+	MatchingEntries int32 `json:"matching_entries"`
+}
+
+// ReconcileReport is the result of the reconciliation
+type ReconcileReport struct {
+	AccountsScanned  int                   `json:"accounts_scanned"`
+	TransfersScanned int                   `json:"transfers_scanned"`
+	Balances         []BalanceDiscrepancy  `json:"balances"`
+	Transfers        []TransferDiscrepancy `json:"transfers"`
+}
+
+// Reconcile checks every account balance against the sum of its entries
+// and every transfer against the entries of its journal entry, reading both in batches
+// The batches are read in one repeatable read transaction, so every check sees the same snapshot of the ledger
+// With arg.Fix customer accounts get an adjustment that records the missing amount without changing the balance
+// once the scan is done, system accounts and transfers are only reported
+func (store *SQLStore) Reconcile(ctx context.Context, arg ReconcileParams) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	if arg.BatchSize <= 0 {
+		arg.BatchSize = DefaultReconcileBatchSize
+	}
+
+	err := store.execTx(ctx, txOptions(arg.TxOptions, repeatableReadOnly), func(q *Queries) error {
+		var err error
+		report, err = scanLedger(ctx, q, arg.BatchSize)
+		return err
+	})
+	if err != nil || !arg.Fix {
+		return report, err
+	}
+
+	for i, discrepancy := range report.Balances {
+		if discrepancy.Kind != AccountKindCustomer {
+			continue
+		}
+
+		report.Balances[i].JournalEntryID, err = store.correctEntries(ctx, discrepancy.AccountID)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// scanLedger reports the discrepancies using queries bound to an already open transaction
+func scanLedger(ctx context.Context, q *Queries, batchSize int32) (ReconcileReport, error) {
+	report := ReconcileReport{
+		Balances:  []BalanceDiscrepancy{},
+		Transfers: []TransferDiscrepancy{},
+	}
+
+	var afterID int64
+	for {
+		totals, err := q.ListAccountEntryTotals(ctx, ListAccountEntryTotalsParams{
+			AfterID:   afterID,
+			BatchSize: batchSize,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, total := range totals {
+			if total.Balance == total.EntriesTotal {
+				continue
+			}
+
+			report.Balances = append(report.Balances, BalanceDiscrepancy{
+				AccountID:    total.ID,
+				Kind:         total.Kind,
+				Currency:     total.Currency,
+				Balance:      total.Balance,
+				EntriesTotal: total.EntriesTotal,
+				Difference:   total.Balance - total.EntriesTotal,
+			})
+		}
+
+		report.AccountsScanned += len(totals)
+		if len(totals) < int(batchSize) {
+			break
+		}
+		afterID = totals[len(totals)-1].ID
+	}
+
+	afterID = 0
+	for {
+		counts, err := q.ListTransferEntryCounts(ctx, ListTransferEntryCountsParams{
+			AfterID:   afterID,
+			BatchSize: batchSize,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, count := range counts {
+			if count.MatchingEntries == 2 {
+				continue
+			}
+
+			report.Transfers = append(report.Transfers, TransferDiscrepancy{
+				TransferID:      count.ID,
+				FromAccountID:   count.FromAccountID,
+				ToAccountID:     count.ToAccountID,
+				Amount:          count.Amount,
+				ToAmount:        count.ToAmount,
+				MatchingEntries: count.MatchingEntries,
+			})
+		}
+
+		report.TransfersScanned += len(counts)
+		if len(counts) < int(batchSize) {
+			break
+		}
+		afterID = counts[len(counts)-1].ID
+	}
+
+	return report, nil
+}
+
+// correctEntries posts the amount the entries of the account are missing against the suspense account
+// The difference is computed again under the account lock, it returns 0 when there is nothing left to correct
+func (store *SQLStore) correctEntries(ctx context.Context, accountID int64) (int64, error) {
+	var journalEntryID int64
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		account, err := lockWithSuspense(ctx, q, accountID)
+		if err != nil {
+			return err
+		}
+
+		entriesTotal, err := q.GetAccountEntriesTotal(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		difference := account.Balance - entriesTotal
+		if difference == 0 {
+			return nil
+		}
+
+		suspense, err := systemAccount(ctx, q, AccountKindSuspense, account.Currency)
+		if err != nil {
+			return err
+		}
+
+		journal, err := postJournal(ctx, q, PostJournalParams{
+			Kind: JournalKindAdjustment,
+			Postings: []Posting{
+				{AccountID: account.ID, Amount: difference, balanceApplied: true},
+				{AccountID: suspense.ID, Amount: -difference},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		journalEntryID = journal.JournalEntry.ID
+		return nil
+	})
+
+	return journalEntryID, err
+}
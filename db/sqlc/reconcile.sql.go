@@ -0,0 +1,133 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: reconcile.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getAccountEntriesTotal = `-- name: GetAccountEntriesTotal :one
+select coalesce(sum(amount), 0)::bigint as entries_total from entries where account_id = $1
+`
+
+func (q *Queries) GetAccountEntriesTotal(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getAccountEntriesTotal, accountID)
+	var entries_total int64
+	err := row.Scan(&entries_total)
+	return entries_total, err
+}
+
+const listAccountEntryTotals = `-- name: ListAccountEntryTotals :many
+select accounts.id, accounts.kind, accounts.currency, accounts.balance,
+       coalesce(sum(entries.amount), 0)::bigint as entries_total
+from accounts
+    left join entries on entries.account_id = accounts.id
+where accounts.id > $1
+group by accounts.id
+order by accounts.id
+limit $2
+`
+
+type ListAccountEntryTotalsParams struct {
+	AfterID   int64 `json:"after_id"`
+	BatchSize int32 `json:"batch_size"`
+}
+
+type ListAccountEntryTotalsRow struct {
+	ID           int64       `json:"id"`
+	Kind         AccountKind `json:"kind"`
+	Currency     string      `json:"currency"`
+	Balance      int64       `json:"balance"`
+	EntriesTotal int64       `json:"entries_total"`
+}
+
+func (q *Queries) ListAccountEntryTotals(ctx context.Context, arg ListAccountEntryTotalsParams) ([]ListAccountEntryTotalsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountEntryTotals, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountEntryTotalsRow{}
+	for rows.Next() {
+		var i ListAccountEntryTotalsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.Currency,
+			&i.Balance,
+			&i.EntriesTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransferEntryCounts = `-- name: ListTransferEntryCounts :many
+select transfers.id, transfers.from_account_id, transfers.to_account_id, transfers.amount, transfers.to_amount,
+       count(entries.id) filter (where (entries.entry_type = 'transfer_debit' and entries.account_id = transfers.from_account_id
+           and entries.amount = -transfers.amount)
+           or (entries.entry_type = 'transfer_credit' and entries.account_id = transfers.to_account_id
+               and entries.amount = transfers.to_amount))::int as matching_entries
+from transfers
+    left join entries on entries.transfer_id = transfers.id
+where transfers.id > $1
+group by transfers.id
+order by transfers.id
+limit $2
+`
+
+type ListTransferEntryCountsParams struct {
+	AfterID   int64 `json:"after_id"`
+	BatchSize int32 `json:"batch_size"`
+}
+
+type ListTransferEntryCountsRow struct {
+	ID              int64 `json:"id"`
+	FromAccountID   int64 `json:"from_account_id"`
+	ToAccountID     int64 `json:"to_account_id"`
+	Amount          int64 `json:"amount"`
+	ToAmount        int64 `json:"to_amount"`
+	MatchingEntries int32 `json:"matching_entries"`
+}
+
+// an entry matches when it is the transfer's debit of the source or its credit of the destination
+func (q *Queries) ListTransferEntryCounts(ctx context.Context, arg ListTransferEntryCountsParams) ([]ListTransferEntryCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferEntryCounts, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransferEntryCountsRow{}
+	for rows.Next() {
+		var i ListTransferEntryCountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.ToAmount,
+			&i.MatchingEntries,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func findBalanceDiscrepancy(report ReconcileReport, accountID int64) (BalanceDiscrepancy, bool) {
+	for _, discrepancy := range report.Balances {
+		if discrepancy.AccountID == accountID {
+			return discrepancy, true
+		}
+	}
+	return BalanceDiscrepancy{}, false
+}
+
+func findTransferDiscrepancy(report ReconcileReport, transferID int64) (TransferDiscrepancy, bool) {
+	for _, discrepancy := range report.Transfers {
+		if discrepancy.TransferID == transferID {
+			return discrepancy, true
+		}
+	}
+	return TransferDiscrepancy{}, false
+}
+
+func TestReconcile(t *testing.T) {
+	store := NewStore(testDB)
+
+	// the balance was set without an entry
+	drifted := createAccountWithBalance(t, 100)
+
+	a1 := createAccountWithBalance(t, 0)
+	a2 := createAccountWithBalance(t, 0)
+	_, err := store.DepositTx(context.Background(), BalanceTxParams{AccountID: a1.ID, Amount: 50})
+	require.NoError(t, err)
+	transferred, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        20,
+	})
+	require.NoError(t, err)
+
+	// the transfer row was written without moving any money
+	unmatched := createRandomTransfer(t)
+
+	report, err := store.Reconcile(context.Background(), ReconcileParams{BatchSize: 2})
+	require.NoError(t, err)
+	require.NotZero(t, report.AccountsScanned)
+	require.NotZero(t, report.TransfersScanned)
+
+	discrepancy, found := findBalanceDiscrepancy(report, drifted.ID)
+	require.True(t, found)
+	require.Equal(t, int64(100), discrepancy.Difference)
+	require.Zero(t, discrepancy.JournalEntryID)
+
+	_, found = findBalanceDiscrepancy(report, a1.ID)
+	require.False(t, found)
+	_, found = findBalanceDiscrepancy(report, a2.ID)
+	require.False(t, found)
+
+	transferDiscrepancy, found := findTransferDiscrepancy(report, unmatched.ID)
+	require.True(t, found)
+	require.Zero(t, transferDiscrepancy.MatchingEntries)
+	_, found = findTransferDiscrepancy(report, transferred.Transfer.ID)
+	require.False(t, found)
+
+	report, err = store.Reconcile(context.Background(), ReconcileParams{BatchSize: 2, Fix: true})
+	require.NoError(t, err)
+
+	discrepancy, found = findBalanceDiscrepancy(report, drifted.ID)
+	require.True(t, found)
+	require.NotZero(t, discrepancy.JournalEntryID)
+
+	// the correction catches the entries up with the balance, the balance itself stays
+	account, err := testQueries.GetAccount(context.Background(), drifted.ID)
+	require.NoError(t, err)
+	require.Equal(t, drifted.Balance, account.Balance)
+	total, err := testQueries.GetAccountEntriesTotal(context.Background(), drifted.ID)
+	require.NoError(t, err)
+	require.Equal(t, drifted.Balance, total)
+
+	suspense, err := systemAccount(context.Background(), testQueries, AccountKindSuspense, drifted.Currency)
+	require.NoError(t, err)
+	total, err = testQueries.GetAccountEntriesTotal(context.Background(), suspense.ID)
+	require.NoError(t, err)
+	require.Equal(t, suspense.Balance, total)
+
+	report, err = store.Reconcile(context.Background(), ReconcileParams{BatchSize: 2})
+	require.NoError(t, err)
+	_, found = findBalanceDiscrepancy(report, drifted.ID)
+	require.False(t, found)
+}
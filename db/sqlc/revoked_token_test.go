@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+	"time"
+)
+
+func TestRevokeToken(t *testing.T) {
+	arg := RevokeTokenParams{
+		ID:        uuid.New(),
+		Username:  util.RandomOwner(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       arg.ID,
+		Username: arg.Username,
+		IssuedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, testQueries.RevokeToken(context.Background(), arg))
+	// revoking twice is a no-op
+	require.NoError(t, testQueries.RevokeToken(context.Background(), arg))
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       arg.ID,
+		Username: arg.Username,
+		IssuedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	arg := RevokeTokenParams{
+		ID:        uuid.New(),
+		Username:  util.RandomOwner(),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	require.NoError(t, testQueries.RevokeToken(context.Background(), arg))
+
+	rows, err := testQueries.DeleteExpiredRevokedTokens(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, rows, int64(1))
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       arg.ID,
+		Username: arg.Username,
+		IssuedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestIsTokenRevokedAfterPasswordChange(t *testing.T) {
+	user := createRandomUser(t)
+	arg := IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now(),
+	}
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	_, err = testQueries.UpdateUserPassword(context.Background(), UpdateUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    util.RandomString(32),
+		PasswordChangedAt: arg.IssuedAt.Add(time.Second),
+	})
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// tokens issued after the change are fine
+	arg.IssuedAt = arg.IssuedAt.Add(2 * time.Second)
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -0,0 +1,370 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: scheduled_transfer.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const advanceScheduledTransfer = `-- name: AdvanceScheduledTransfer :one
+update scheduled_transfers
+set next_run_at = $1,
+    last_run_at = $2,
+    status      = $3
+where id = $4
+returning id, owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at, last_run_at, status, created_at
+`
+
+type AdvanceScheduledTransferParams struct {
+	NextRunAt time.Time               `json:"next_run_at"`
+	LastRunAt sql.NullTime            `json:"last_run_at"`
+	Status    ScheduledTransferStatus `json:"status"`
+	ID        int64                   `json:"id"`
+}
+
+func (q *Queries) AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, advanceScheduledTransfer,
+		arg.NextRunAt,
+		arg.LastRunAt,
+		arg.Status,
+		arg.ID,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.StartAt,
+		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const cancelScheduledTransfer = `-- name: CancelScheduledTransfer :one
+update scheduled_transfers set status = 'cancelled' where id = $1 and status = 'active' returning id, owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at, last_run_at, status, created_at
+`
+
+func (q *Queries) CancelScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, cancelScheduledTransfer, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.StartAt,
+		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransfer = `-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at
+) VALUES (
+             $1, $2, $3, $4,
+             $5, $6, $6
+         )
+RETURNING id, owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at, last_run_at, status, created_at
+`
+
+type CreateScheduledTransferParams struct {
+	Owner         string     `json:"owner"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        int64      `json:"amount"`
+	Recurrence    Recurrence `json:"recurrence"`
+	StartAt       time.Time  `json:"start_at"`
+}
+
+func (q *Queries) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransfer,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Recurrence,
+		arg.StartAt,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.StartAt,
+		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransferRun = `-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+    scheduled_transfer_id, transfer_id, status, error, scheduled_for
+) VALUES (
+             $1, $2, $3, $4, $5
+         )
+RETURNING id, scheduled_transfer_id, transfer_id, status, error, scheduled_for, created_at
+`
+
+type CreateScheduledTransferRunParams struct {
+	ScheduledTransferID int64              `json:"scheduled_transfer_id"`
+	TransferID          sql.NullInt64      `json:"transfer_id"`
+	Status              ScheduledRunStatus `json:"status"`
+	Error               string             `json:"error"`
+	ScheduledFor        time.Time          `json:"scheduled_for"`
+}
+
+func (q *Queries) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransferRun,
+		arg.ScheduledTransferID,
+		arg.TransferID,
+		arg.Status,
+		arg.Error,
+		arg.ScheduledFor,
+	)
+	var i ScheduledTransferRun
+	err := row.Scan(
+		&i.ID,
+		&i.ScheduledTransferID,
+		&i.TransferID,
+		&i.Status,
+		&i.Error,
+		&i.ScheduledFor,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDueScheduledTransferForUpdate = `-- name: GetDueScheduledTransferForUpdate :one
+select id, owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at, last_run_at, status, created_at from scheduled_transfers
+where status = 'active' and next_run_at <= $1
+order by next_run_at
+limit 1
+for update skip locked
+`
+
+// skipping the rows other workers hold lets several server instances share the work
+func (q *Queries) GetDueScheduledTransferForUpdate(ctx context.Context, now time.Time) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getDueScheduledTransferForUpdate, now)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.StartAt,
+		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getScheduledTransfer = `-- name: GetScheduledTransfer :one
+select id, owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at, last_run_at, status, created_at from scheduled_transfers where id = $1 limit 1
+`
+
+func (q *Queries) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledTransfer, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.StartAt,
+		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getScheduledTransferForUpdate = `-- name: GetScheduledTransferForUpdate :one
+select id, owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at, last_run_at, status, created_at from scheduled_transfers where id = $1 limit 1 for no key update
+`
+
+func (q *Queries) GetScheduledTransferForUpdate(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledTransferForUpdate, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.StartAt,
+		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listScheduledTransferRuns = `-- name: ListScheduledTransferRuns :many
+select id, scheduled_transfer_id, transfer_id, status, error, scheduled_for, created_at from scheduled_transfer_runs where scheduled_transfer_id = $1 order by id desc limit $2 offset $3
+`
+
+type ListScheduledTransferRunsParams struct {
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	Limit               int32 `json:"limit"`
+	Offset              int32 `json:"offset"`
+}
+
+func (q *Queries) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransferRuns, arg.ScheduledTransferID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransferRun{}
+	for rows.Next() {
+		var i ScheduledTransferRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.ScheduledTransferID,
+			&i.TransferID,
+			&i.Status,
+			&i.Error,
+			&i.ScheduledFor,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransfers = `-- name: ListScheduledTransfers :many
+select scheduled_transfers.id, scheduled_transfers.owner, scheduled_transfers.from_account_id, scheduled_transfers.to_account_id, scheduled_transfers.amount, scheduled_transfers.recurrence, scheduled_transfers.start_at, scheduled_transfers.next_run_at, scheduled_transfers.last_run_at, scheduled_transfers.status, scheduled_transfers.created_at, accounts.currency
+from scheduled_transfers
+    join accounts on accounts.id = scheduled_transfers.from_account_id
+where scheduled_transfers.owner = $1
+order by scheduled_transfers.id
+limit $2 offset $3
+`
+
+type ListScheduledTransfersParams struct {
+	Owner  string `json:"owner"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+type ListScheduledTransfersRow struct {
+	ScheduledTransfer ScheduledTransfer `json:"scheduled_transfer"`
+	Currency          string            `json:"currency"`
+}
+
+func (q *Queries) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ListScheduledTransfersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransfers, arg.Owner, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListScheduledTransfersRow{}
+	for rows.Next() {
+		var i ListScheduledTransfersRow
+		if err := rows.Scan(
+			&i.ScheduledTransfer.ID,
+			&i.ScheduledTransfer.Owner,
+			&i.ScheduledTransfer.FromAccountID,
+			&i.ScheduledTransfer.ToAccountID,
+			&i.ScheduledTransfer.Amount,
+			&i.ScheduledTransfer.Recurrence,
+			&i.ScheduledTransfer.StartAt,
+			&i.ScheduledTransfer.NextRunAt,
+			&i.ScheduledTransfer.LastRunAt,
+			&i.ScheduledTransfer.Status,
+			&i.ScheduledTransfer.CreatedAt,
+			&i.Currency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateScheduledTransfer = `-- name: UpdateScheduledTransfer :one
+update scheduled_transfers
+set amount      = $1,
+    recurrence  = $2,
+    start_at    = $3,
+    next_run_at = $3
+where id = $4 and status = 'active'
+returning id, owner, from_account_id, to_account_id, amount, recurrence, start_at, next_run_at, last_run_at, status, created_at
+`
+
+type UpdateScheduledTransferParams struct {
+	Amount     int64      `json:"amount"`
+	Recurrence Recurrence `json:"recurrence"`
+	StartAt    time.Time  `json:"start_at"`
+	ID         int64      `json:"id"`
+}
+
+func (q *Queries) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledTransfer,
+		arg.Amount,
+		arg.Recurrence,
+		arg.StartAt,
+		arg.ID,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.StartAt,
+		&i.NextRunAt,
+		&i.LastRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
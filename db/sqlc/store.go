@@ -3,30 +3,111 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
+)
+
+var (
+	// ErrInsufficientFunds is returned by TransferTx when the source account can't cover the amount
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrAccountNotActive is returned when money is moved to or from a frozen or closed account
+	ErrAccountNotActive = errors.New("account is not active")
+	// ErrAccountClosed is returned by admin operations on a closed account
+	ErrAccountClosed = errors.New("account is closed")
+	// ErrBalanceOverflow is returned when a posting would take a balance beyond what its bigint column holds
+	ErrBalanceOverflow = errors.New("balance would overflow")
+	// ErrSystemAccount is returned when a transfer involves one of the bank's own ledger accounts
+	ErrSystemAccount = errors.New("system accounts can't take part in transfers")
+	// ErrVerifyEmailInvalid is returned by VerifyEmailTx when the code is wrong, used or expired
+	ErrVerifyEmailInvalid = errors.New("email verification code is invalid or expired")
+	// ErrEmailAlreadyVerified is returned by ResendVerifyEmailTx when there is nothing left to verify
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+	// ErrAfterCommit wraps the error of a callback that runs once its transaction committed, the changes are kept
+	ErrAfterCommit = errors.New("transaction committed but its follow-up failed")
+	// ErrPasswordResetInvalid is returned by ResetPasswordTx when the code is wrong, used or expired
+	ErrPasswordResetInvalid = errors.New("password reset code is invalid or expired")
+	// ErrScheduledRunFailed wraps an unexpected error of RunScheduledTransferTx once the failed run is recorded
+	// and the schedule backed off, so the other due schedules can run meanwhile
+	ErrScheduledRunFailed = errors.New("scheduled transfer run failed")
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	IdempotentTransferTx(ctx context.Context, arg IdempotentTransferTxParams) (TransferTxResult, error)
+	DepositTx(ctx context.Context, arg BalanceTxParams) (BalanceTxResult, error)
+	WithdrawTx(ctx context.Context, arg BalanceTxParams) (BalanceTxResult, error)
+	CloseAccountTx(ctx context.Context, accountID int64) (Account, error)
+	SetAccountStatusTx(ctx context.Context, arg SetAccountStatusTxParams) (Account, error)
+	AdjustBalanceTx(ctx context.Context, arg BalanceTxParams) (BalanceTxResult, error)
+	SetBalanceTx(ctx context.Context, arg BalanceTxParams) (BalanceTxResult, error)
+	PostJournal(ctx context.Context, arg PostJournalParams) (PostJournalResult, error)
+	Reconcile(ctx context.Context, arg ReconcileParams) (ReconcileReport, error)
+	RunScheduledTransferTx(ctx context.Context, now time.Time) (ScheduledTransferRunResult, error)
+	DispatchOutboxTx(ctx context.Context, arg DispatchOutboxTxParams) (int, error)
+	DeliverWebhooksTx(ctx context.Context, arg DeliverWebhooksTxParams) ([]WebhookDelivery, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	ResendVerifyEmailTx(ctx context.Context, arg ResendVerifyEmailTxParams) (VerifyEmail, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error)
+	CreatePasswordResetTx(ctx context.Context, arg CreatePasswordResetTxParams) (CreatePasswordResetTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	TxStats() TxStats
 }
 
 // SQLStore provides all functions to execute db queries and transactions
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	db          *sql.DB
+	retryPolicy RetryPolicy
+	counters    *txCounters
 }
 
 func NewStore(db *sql.DB) Store {
+	return NewStoreWithRetryPolicy(db, DefaultRetryPolicy)
+}
+
+// NewStoreWithRetryPolicy creates a store that retries transactions aborted by serialization failures and deadlocks
+func NewStoreWithRetryPolicy(db *sql.DB, policy RetryPolicy) Store {
 	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+		db:          db,
+		Queries:     New(db),
+		retryPolicy: policy,
+		counters:    &txCounters{},
 	}
 }
 
-// execTx executes a function within a database transaction
-func (store *SQLStore) execTx(ctx context.Context, fn func(queries *Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+// Isolation levels the transactions run at unless their params ask for another one
+var (
+	// readCommitted fits the transactions that lock the rows they check before changing them
+	readCommitted = &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	// repeatableReadOnly fits the read-only transactions that need one snapshot across several queries
+	repeatableReadOnly = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	// serializable fits the transactions that check rows they don't lock, a concurrent change of them
+	// aborts the transaction with a serialization failure and execTx runs it again
+	serializable = &sql.TxOptions{Isolation: sql.LevelSerializable}
+)
+
+// txOptions returns opts, or fallback when the caller didn't pick any
+func txOptions(opts *sql.TxOptions, fallback *sql.TxOptions) *sql.TxOptions {
+	if opts == nil {
+		return fallback
+	}
+	return opts
+}
+
+// execTx executes a function within a database transaction started with opts
+// fn is run again in a new transaction when the previous one hit a serialization failure or a deadlock,
+// so it must not have side effects outside of queries
+func (store *SQLStore) execTx(ctx context.Context, opts *sql.TxOptions, fn func(queries *Queries) error) error {
+	return store.retryTx(ctx, func() error {
+		return store.runTx(ctx, opts, fn)
+	})
+}
+
+func (store *SQLStore) runTx(ctx context.Context, opts *sql.TxOptions, fn func(queries *Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -35,7 +116,7 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(queries *Queries) err
 	err = fn(q)
 	if err != nil {
 		if rbError := tx.Rollback(); rbError != nil {
-			return fmt.Errorf("tx err: %v, rb err: %v", err, rbError)
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbError)
 		}
 
 		return err
@@ -45,10 +126,16 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(queries *Queries) err
 }
 
 // TransferTxParams contains the input parameters of the transfer transaction
+// ToAmount and ExchangeRate are only needed when the accounts hold different currencies,
+// otherwise the destination is credited with Amount at a rate of 1
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64   `json:"from_account_id"`
+	ToAccountID   int64   `json:"to_account_id"`
+	Amount        int64   `json:"amount"`
+	ToAmount      int64   `json:"to_amount"`
+	ExchangeRate  float64 `json:"exchange_rate"`
+	// TxOptions overrides the read committed isolation, the accounts are locked before their balances are checked
+	TxOptions *sql.TxOptions `json:"-"`
 }
 
 // TransferTxResult is the result of the transfer transaction
@@ -62,66 +149,114 @@ type TransferTxResult struct {
 
 // TransferTx performs a money transfer from one account to the other
 // It creates a transfer record, add account entries and update accounts' balance withing a single database transaction
+// The transaction is rolled back with ErrAccountNotActive if either account is frozen or closed
+// and with ErrInsufficientFunds if the source balance drops below its overdraft limit
+// The entries are the postings of a transfer journal entry, EventTransferCompleted is written to the outbox along with them
+// and the webhooks of both accounts get their transfer and balance change deliveries queued
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, txOptions(arg.TxOptions, readCommitted), func(q *Queries) error {
 		var err error
+		result, err = transfer(ctx, q, arg)
+		return err
+	})
 
-		result.Transfer, err = q.CreateTransfer(context.Background(), CreateTransferParams{
-			arg.FromAccountID,
-			arg.ToAccountID,
-			arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	return result, err
+}
 
-		result.FromEntry, err = q.CreateEntry(context.Background(), CreateEntryParams{
-			arg.FromAccountID,
-			-arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+// transfer moves money between accounts using queries bound to an already open transaction
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
 
-		result.ToEntry, err = q.CreateEntry(context.Background(), CreateEntryParams{
-			arg.ToAccountID,
-			arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	if arg.ExchangeRate == 0 {
+		arg.ToAmount = arg.Amount
+		arg.ExchangeRate = 1
+	}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-		}
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+	})
+	if err != nil {
+		return result, err
+	}
 
-		return err
+	postings, err := transferPostings(ctx, q, arg)
+	if err != nil {
+		return result, err
+	}
+
+	journal, err := postJournal(ctx, q, PostJournalParams{
+		Kind:       JournalKindTransfer,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		Postings:   postings,
 	})
+	if err != nil {
+		return result, err
+	}
 
-	return result, err
+	last := len(postings) - 1
+	result.FromEntry, result.ToEntry = journal.Entries[0], journal.Entries[last]
+	result.FromAccount, result.ToAccount = journal.Accounts[0], journal.Accounts[last]
+
+	if result.FromAccount.Status != AccountStatusActive || result.ToAccount.Status != AccountStatusActive {
+		return result, ErrAccountNotActive
+	}
+
+	if result.FromAccount.Balance < -result.FromAccount.OverdraftLimit {
+		return result, ErrInsufficientFunds
+	}
+
+	if err := writeTransferEvent(ctx, q, result); err != nil {
+		return result, err
+	}
+
+	return result, queueTransferWebhooks(ctx, q, result)
 }
 
-func addMoney(
-	c context.Context,
-	q *Queries,
-	accountID1 int64,
-	amount1 int64,
-	accountID2 int64,
-	amount2 int64,
-) (account1 Account, account2 Account, err error) {
-	account1, err = q.AddAccountBalance(c, AddAccountBalanceParams{amount1, accountID1})
+// transferPostings debits the source and credits the destination
+// When the currencies differ the suspense accounts of both currencies take the other side, so each currency balances
+func transferPostings(ctx context.Context, q *Queries, arg TransferTxParams) ([]Posting, error) {
+	from, err := q.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := q.GetAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.Kind != AccountKindCustomer || to.Kind != AccountKindCustomer {
+		return nil, ErrSystemAccount
+	}
+
+	if from.Currency == to.Currency {
+		return []Posting{
+			{AccountID: from.ID, Amount: -arg.Amount},
+			{AccountID: to.ID, Amount: arg.ToAmount},
+		}, nil
+	}
+
+	fromSuspense, err := systemAccount(ctx, q, AccountKindSuspense, from.Currency)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	account2, err = q.AddAccountBalance(c, AddAccountBalanceParams{amount2, accountID2})
+	toSuspense, err := systemAccount(ctx, q, AccountKindSuspense, to.Currency)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	return
+	return []Posting{
+		{AccountID: from.ID, Amount: -arg.Amount},
+		{AccountID: fromSuspense.ID, Amount: arg.Amount},
+		{AccountID: toSuspense.ID, Amount: -arg.ToAmount},
+		{AccountID: to.ID, Amount: arg.ToAmount},
+	}, nil
 }
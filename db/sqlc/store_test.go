@@ -4,29 +4,32 @@ import (
 	"context"
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"math"
 	"testing"
+	"time"
 )
 
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDB)
 
-	a1 := createRandomAccount(t)
-	a2 := createRandomAccount(t)
-	fmt.Println(">>> before:", a1.Balance, a2.Balance)
-
 	// run n concurrent transfer transactions
 	n := 5
 	amount := int64(10)
 
+	a1 := createAccountWithBalance(t, util.RandomInt(int64(n)*amount, 1000))
+	a2 := createRandomAccount(t)
+	fmt.Println(">>> before:", a1.Balance, a2.Balance)
+
 	results := make(chan TransferTxResult)
 	errs := make(chan error)
 
 	for i := 0; i < n; i++ {
 		go func() {
 			result, err := store.TransferTx(context.Background(), TransferTxParams{
-				a1.ID,
-				a2.ID,
-				amount,
+				FromAccountID: a1.ID,
+				ToAccountID:   a2.ID,
+				Amount:        amount,
 			})
 
 			results <- result
@@ -50,6 +53,8 @@ func TestTransferTx(t *testing.T) {
 		require.Equal(t, a1.ID, transfer.FromAccountID)
 		require.Equal(t, a2.ID, transfer.ToAccountID)
 		require.Equal(t, amount, transfer.Amount)
+		require.Equal(t, amount, transfer.ToAmount)
+		require.Equal(t, 1.0, transfer.ExchangeRate)
 		require.NotZero(t, transfer.ID)
 		require.NotZero(t, transfer.CreatedAt)
 
@@ -117,14 +122,15 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadlock(t *testing.T) {
 	store := NewStore(testDB)
 
-	a1 := createRandomAccount(t)
-	a2 := createRandomAccount(t)
-	fmt.Println(">>> before:", a1.Balance, a2.Balance)
-
 	// run n concurrent transfer transactions
 	n := 10
 	amount := int64(10)
 
+	// each account must be able to cover every outgoing transfer before any incoming one lands
+	a1 := createAccountWithBalance(t, util.RandomInt(int64(n)*amount, 1000))
+	a2 := createAccountWithBalance(t, util.RandomInt(int64(n)*amount, 1000))
+	fmt.Println(">>> before:", a1.Balance, a2.Balance)
+
 	errs := make(chan error)
 
 	for i := 0; i < n; i++ {
@@ -138,9 +144,9 @@ func TestTransferTxDeadlock(t *testing.T) {
 
 		go func() {
 			_, err := store.TransferTx(context.Background(), TransferTxParams{
-				fromAccountID,
-				toAccountID,
-				amount,
+				FromAccountID: fromAccountID,
+				ToAccountID:   toAccountID,
+				Amount:        amount,
 			})
 
 			errs <- err
@@ -166,3 +172,151 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, a1.Balance, updatedAccount1.Balance)
 	require.Equal(t, a2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	a1 := createRandomAccount(t)
+	a2 := createRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        a1.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// the whole transaction must be rolled back
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, a1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), a2.ID)
+	require.NoError(t, err)
+	require.Equal(t, a2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxBalanceOverflow(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, math.MaxInt64-5)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrBalanceOverflow)
+
+	// the whole transaction must be rolled back
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, a1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), a2.ID)
+	require.NoError(t, err)
+	require.Equal(t, a2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxCancelled(t *testing.T) {
+	store := NewStore(testDB)
+
+	a1 := createRandomAccount(t)
+	a2 := createRandomAccount(t)
+
+	// another transaction holds the account rows, so the transfer blocks after writing the transfer and entries
+	lock, err := testDB.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	defer lock.Rollback()
+
+	_, err = lock.Exec("SELECT id FROM accounts WHERE id IN ($1, $2) FOR NO KEY UPDATE", a1.ID, a2.ID)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        1,
+	})
+	require.Error(t, err)
+	require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	require.NoError(t, lock.Rollback())
+
+	// nothing written before the cancellation must remain
+	for _, account := range []Account{a1, a2} {
+		updated, err := testQueries.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+
+		entries, err := testQueries.ListEntries(context.Background(), ListEntriesParams{AccountID: account.ID, Limit: 5})
+		require.NoError(t, err)
+		require.Empty(t, entries)
+
+		transfers, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+			AccountID: account.ID,
+			Outgoing:  true,
+			Incoming:  true,
+			PageSize:  5,
+		})
+		require.NoError(t, err)
+		require.Empty(t, transfers)
+	}
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	a1 := createRandomAccount(t)
+	a2 := createRandomAccount(t)
+
+	limit := int64(100)
+	a1, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             a1.ID,
+		OverdraftLimit: limit,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        a1.Balance + limit,
+	})
+	require.NoError(t, err)
+	require.Equal(t, -limit, result.FromAccount.Balance)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestTransferTxExchangeRate(t *testing.T) {
+	store := NewStore(testDB)
+
+	a1 := createAccountWithBalance(t, 1000)
+	a2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        100,
+		ToAmount:      92,
+		ExchangeRate:  0.92,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(100), result.Transfer.Amount)
+	require.Equal(t, int64(92), result.Transfer.ToAmount)
+	require.Equal(t, 0.92, result.Transfer.ExchangeRate)
+
+	require.Equal(t, int64(-100), result.FromEntry.Amount)
+	require.Equal(t, int64(92), result.ToEntry.Amount)
+	require.Equal(t, a1.Balance-100, result.FromAccount.Balance)
+	require.Equal(t, a2.Balance+92, result.ToAccount.Balance)
+}
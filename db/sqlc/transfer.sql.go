@@ -7,25 +7,34 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
-    from_account_id, to_account_id, amount
+    from_account_id, to_account_id, amount, to_amount, exchange_rate
 ) VALUES (
-             $1, $2, $3
+             $1, $2, $3, $4, $5
          )
-RETURNING id, from_account_id, to_account_id, amount, created_at
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64   `json:"from_account_id"`
+	ToAccountID   int64   `json:"to_account_id"`
+	Amount        int64   `json:"amount"`
+	ToAmount      int64   `json:"to_amount"`
+	ExchangeRate  float64 `json:"exchange_rate"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -33,12 +42,14 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-select id, from_account_id, to_account_id, amount, created_at from transfers where id = $1 limit 1
+select id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate from transfers where id = $1 limit 1
 `
 
 func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
@@ -50,12 +61,94 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
 	)
 	return i, err
 }
 
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+select transfers.id, transfers.from_account_id, transfers.to_account_id, transfers.amount, transfers.created_at, transfers.to_amount, transfers.exchange_rate, fa.currency as from_currency, ta.currency as to_currency
+from transfers
+    join accounts fa on fa.id = transfers.from_account_id
+    join accounts ta on ta.id = transfers.to_account_id
+where (($1::bool and transfers.from_account_id = $2)
+    or ($3::bool and transfers.to_account_id = $2))
+  and transfers.id > $4
+  and ($5::timestamptz is null or transfers.created_at >= $5)
+  and ($6::timestamptz is null or transfers.created_at < $6)
+  -- amounts are compared in the currency of the account: what it sent or what it received
+  and ($7::bigint is null or (case when transfers.from_account_id = $2
+      then transfers.amount else transfers.to_amount end) >= $7)
+  and ($8::bigint is null or (case when transfers.from_account_id = $2
+      then transfers.amount else transfers.to_amount end) <= $8)
+order by transfers.id
+limit $9
+`
+
+type ListAccountTransfersParams struct {
+	Outgoing  bool          `json:"outgoing"`
+	AccountID int64         `json:"account_id"`
+	Incoming  bool          `json:"incoming"`
+	AfterID   int64         `json:"after_id"`
+	FromTime  sql.NullTime  `json:"from_time"`
+	ToTime    sql.NullTime  `json:"to_time"`
+	MinAmount sql.NullInt64 `json:"min_amount"`
+	MaxAmount sql.NullInt64 `json:"max_amount"`
+	PageSize  int32         `json:"page_size"`
+}
+
+type ListAccountTransfersRow struct {
+	Transfer     Transfer `json:"transfer"`
+	FromCurrency string   `json:"from_currency"`
+	ToCurrency   string   `json:"to_currency"`
+}
+
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]ListAccountTransfersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfers,
+		arg.Outgoing,
+		arg.AccountID,
+		arg.Incoming,
+		arg.AfterID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountTransfersRow{}
+	for rows.Next() {
+		var i ListAccountTransfersRow
+		if err := rows.Scan(
+			&i.Transfer.ID,
+			&i.Transfer.FromAccountID,
+			&i.Transfer.ToAccountID,
+			&i.Transfer.Amount,
+			&i.Transfer.CreatedAt,
+			&i.Transfer.ToAmount,
+			&i.Transfer.ExchangeRate,
+			&i.FromCurrency,
+			&i.ToCurrency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-select id, from_account_id, to_account_id, amount, created_at from transfers where from_account_id = $1 or to_account_id = $2 order by id limit $3 offset $4
+select id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate from transfers where from_account_id = $1 or to_account_id = $2 order by id limit $3 offset $4
 `
 
 type ListTransfersParams struct {
@@ -85,6 +178,8 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
 		); err != nil {
 			return nil, err
 		}
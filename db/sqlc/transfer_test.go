@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"github.com/stretchr/testify/require"
 	"github.com/vadym-98/simple_bank/util"
 	"testing"
@@ -12,10 +13,13 @@ func createRandomTransfer(t *testing.T) Transfer {
 	a1 := createRandomAccount(t)
 	a2 := createRandomAccount(t)
 
+	amount := util.RandomMoney()
 	arg := CreateTransferParams{
 		a1.ID,
 		a2.ID,
-		util.RandomMoney(),
+		amount,
+		amount,
+		1,
 	}
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
@@ -24,6 +28,8 @@ func createRandomTransfer(t *testing.T) Transfer {
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
+	require.Equal(t, arg.ToAmount, transfer.ToAmount)
+	require.Equal(t, arg.ExchangeRate, transfer.ExchangeRate)
 
 	return transfer
 }
@@ -59,10 +65,13 @@ func TestListTransfers(t *testing.T) {
 
 	for _, a := range fromAccounts {
 		for _, b := range toAccounts {
+			amount := util.RandomMoney()
 			arg := CreateTransferParams{
 				a.ID,
 				b.ID,
-				util.RandomMoney(),
+				amount,
+				amount,
+				1,
 			}
 			transfer, err := testQueries.CreateTransfer(context.Background(), arg)
 			require.NoError(t, err)
@@ -88,3 +97,100 @@ func TestListTransfers(t *testing.T) {
 		}
 	}
 }
+
+func TestListAccountTransfers(t *testing.T) {
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+
+	for i := 0; i < 6; i++ {
+		arg := CreateTransferParams{
+			FromAccountID: account.ID,
+			ToAccountID:   other.ID,
+			Amount:        int64(i + 1),
+			ToAmount:      int64(i + 1),
+			ExchangeRate:  1,
+		}
+		if i%2 == 1 {
+			arg.FromAccountID, arg.ToAccountID = other.ID, account.ID
+		}
+
+		_, err := testQueries.CreateTransfer(context.Background(), arg)
+		require.NoError(t, err)
+	}
+
+	arg := ListAccountTransfersParams{
+		AccountID: account.ID,
+		Outgoing:  true,
+		Incoming:  true,
+		PageSize:  10,
+	}
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 6)
+
+	arg.Incoming = false
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 3)
+	for _, tr := range transfers {
+		require.Equal(t, account.ID, tr.Transfer.FromAccountID)
+		require.Equal(t, account.Currency, tr.FromCurrency)
+		require.Equal(t, other.Currency, tr.ToCurrency)
+	}
+
+	arg.Incoming = true
+	arg.Outgoing = false
+	arg.MinAmount = sql.NullInt64{Int64: 3, Valid: true}
+	arg.MaxAmount = sql.NullInt64{Int64: 4, Valid: true}
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, account.ID, transfers[0].Transfer.ToAccountID)
+	require.Equal(t, int64(4), transfers[0].Transfer.Amount)
+
+	arg = ListAccountTransfersParams{
+		AccountID: account.ID,
+		Outgoing:  true,
+		Incoming:  true,
+		AfterID:   transfers[0].Transfer.ID,
+		PageSize:  10,
+	}
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+}
+
+func TestListAccountTransfersAmountInAccountCurrency(t *testing.T) {
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+
+	_, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        1000,
+		ToAmount:      10,
+		ExchangeRate:  0.01,
+	})
+	require.NoError(t, err)
+
+	arg := ListAccountTransfersParams{
+		AccountID: account.ID,
+		Incoming:  true,
+		MaxAmount: sql.NullInt64{Int64: 20, Valid: true},
+		PageSize:  10,
+	}
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+
+	// the sender sees the amount it paid
+	arg = ListAccountTransfersParams{
+		AccountID: other.ID,
+		Outgoing:  true,
+		MaxAmount: sql.NullInt64{Int64: 20, Valid: true},
+		PageSize:  10,
+	}
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+}
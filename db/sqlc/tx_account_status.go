@@ -0,0 +1,36 @@
+package db
+
+import "context"
+
+// SetAccountStatusTxParams contains the input parameters of the account status change
+type SetAccountStatusTxParams struct {
+	AccountID int64         `json:"account_id"`
+	Status    AccountStatus `json:"status"`
+}
+
+// SetAccountStatusTx freezes or reactivates an account
+// Closed accounts stay closed, the transaction is rolled back with ErrAccountClosed for them
+func (store *SQLStore) SetAccountStatusTx(ctx context.Context, arg SetAccountStatusTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+
+		account, err = q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status == AccountStatusClosed {
+			return ErrAccountClosed
+		}
+
+		account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			Status: arg.Status,
+			ID:     arg.AccountID,
+		})
+		return err
+	})
+
+	return account, err
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSetAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccount(t)
+
+	frozen, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, frozen.Status)
+
+	active, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusActive,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, active.Status)
+}
+
+func TestSetAccountStatusTxClosedAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountWithBalance(t, 0)
+	_, err := store.CloseAccountTx(context.Background(), account.ID)
+	require.NoError(t, err)
+
+	_, err = store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusActive,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// BalanceTxParams contains the input parameters of the deposit and withdrawal transactions
+type BalanceTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+	// TxOptions overrides the read committed isolation, the accounts are locked before their balances are checked
+	TxOptions *sql.TxOptions `json:"-"`
+}
+
+// BalanceTxResult is the result of the deposit and withdrawal transactions
+type BalanceTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// DepositTx adds money to an account
+// It creates an account entry, updates the account balance and queues the balance change for the webhooks of the account
+// within a single database transaction
+// The transaction is rolled back with ErrAccountNotActive if the account is frozen or closed
+func (store *SQLStore) DepositTx(ctx context.Context, arg BalanceTxParams) (BalanceTxResult, error) {
+	var result BalanceTxResult
+
+	err := store.execTx(ctx, txOptions(arg.TxOptions, readCommitted), func(q *Queries) error {
+		var err error
+		result, err = changeBalance(ctx, q, JournalKindDeposit, arg.AccountID, arg.Amount)
+		return err
+	})
+
+	return result, err
+}
+
+// WithdrawTx takes money from an account
+// The transaction is rolled back with ErrInsufficientFunds if the balance drops below the overdraft limit
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg BalanceTxParams) (BalanceTxResult, error) {
+	var result BalanceTxResult
+
+	err := store.execTx(ctx, txOptions(arg.TxOptions, readCommitted), func(q *Queries) error {
+		var err error
+		result, err = changeBalance(ctx, q, JournalKindWithdrawal, arg.AccountID, -arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		if result.Account.Balance < -result.Account.OverdraftLimit {
+			return ErrInsufficientFunds
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// changeBalance posts amount to the account against the suspense account of its currency,
+// which stands for the money held outside of the bank
+func changeBalance(ctx context.Context, q *Queries, kind JournalKind, accountID int64, amount int64) (BalanceTxResult, error) {
+	var result BalanceTxResult
+
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return result, err
+	}
+
+	result, err = postAgainstSuspense(ctx, q, kind, account, amount)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Account.Status != AccountStatusActive {
+		return result, ErrAccountNotActive
+	}
+
+	return result, nil
+}
+
+// postAgainstSuspense posts amount to the account against its suspense account
+// and queues WebhookEventBalanceChanged for the webhooks of the account
+func postAgainstSuspense(ctx context.Context, q *Queries, kind JournalKind, account Account, amount int64) (BalanceTxResult, error) {
+	var result BalanceTxResult
+
+	if account.Kind != AccountKindCustomer {
+		return result, ErrSystemAccount
+	}
+
+	suspense, err := systemAccount(ctx, q, AccountKindSuspense, account.Currency)
+	if err != nil {
+		return result, err
+	}
+
+	journal, err := postJournal(ctx, q, PostJournalParams{
+		Kind: kind,
+		Postings: []Posting{
+			{AccountID: account.ID, Amount: amount},
+			{AccountID: suspense.ID, Amount: -amount},
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Entry = journal.Entries[0]
+	result.Account = journal.Accounts[0]
+
+	return result, queueWebhookDeliveries(ctx, q, AccountActivityEvent{
+		AccountID: result.Account.ID,
+		Amount:    amount,
+		Currency:  result.Account.Currency,
+		Balance:   result.Account.Balance,
+		CreatedAt: result.Entry.CreatedAt,
+	}, WebhookEventBalanceChanged)
+}
+
+// lockWithSuspense locks the account together with the suspense account of its currency in account ID order,
+// the same order postJournal updates them in, and returns the locked account
+func lockWithSuspense(ctx context.Context, q *Queries, accountID int64) (Account, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return account, err
+	}
+
+	if account.Kind != AccountKindCustomer {
+		return account, ErrSystemAccount
+	}
+
+	suspense, err := systemAccount(ctx, q, AccountKindSuspense, account.Currency)
+	if err != nil {
+		return account, err
+	}
+
+	if suspense.ID < account.ID {
+		if _, err = q.GetAccountForUpdate(ctx, suspense.ID); err != nil {
+			return account, err
+		}
+		return q.GetAccountForUpdate(ctx, account.ID)
+	}
+
+	account, err = q.GetAccountForUpdate(ctx, account.ID)
+	if err != nil {
+		return account, err
+	}
+	_, err = q.GetAccountForUpdate(ctx, suspense.ID)
+	return account, err
+}
+
+// AdjustBalanceTx applies a manual correction made by an admin, the amount may be negative
+// Unlike deposits and withdrawals it works on frozen accounts and ignores the overdraft limit,
+// only closed accounts are rejected with ErrAccountClosed
+func (store *SQLStore) AdjustBalanceTx(ctx context.Context, arg BalanceTxParams) (BalanceTxResult, error) {
+	var result BalanceTxResult
+
+	err := store.execTx(ctx, txOptions(arg.TxOptions, readCommitted), func(q *Queries) error {
+		account, err := lockWithSuspense(ctx, q, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status == AccountStatusClosed {
+			return ErrAccountClosed
+		}
+
+		result, err = postAgainstSuspense(ctx, q, JournalKindAdjustment, account, arg.Amount)
+		return err
+	})
+
+	return result, err
+}
+
+// SetBalanceTx sets the balance an admin asked for by posting the difference as an adjustment
+// An unchanged balance writes nothing and returns an empty Entry
+func (store *SQLStore) SetBalanceTx(ctx context.Context, arg BalanceTxParams) (BalanceTxResult, error) {
+	var result BalanceTxResult
+
+	err := store.execTx(ctx, txOptions(arg.TxOptions, readCommitted), func(q *Queries) error {
+		account, err := lockWithSuspense(ctx, q, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status == AccountStatusClosed {
+			return ErrAccountClosed
+		}
+
+		if account.Balance == arg.Amount {
+			result = BalanceTxResult{Account: account}
+			return nil
+		}
+
+		result, err = postAgainstSuspense(ctx, q, JournalKindAdjustment, account, arg.Amount-account.Balance)
+		return err
+	})
+
+	return result, err
+}
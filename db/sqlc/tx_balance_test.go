@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"math"
+	"testing"
+)
+
+func TestDepositTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccount(t)
+	amount := util.RandomInt(1, 100)
+
+	result, err := store.DepositTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, amount, result.Entry.Amount)
+	require.NotZero(t, result.Entry.ID)
+
+	require.Equal(t, account.ID, result.Account.ID)
+	require.Equal(t, account.Balance+amount, result.Account.Balance)
+}
+
+func TestDepositTxBalanceOverflow(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountInCurrency(t, util.RandomCurrency(), math.MaxInt64-5)
+
+	_, err := store.DepositTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    10,
+	})
+	require.ErrorIs(t, err, ErrBalanceOverflow)
+
+	updated, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updated.Balance)
+}
+
+func TestWithdrawTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	amount := util.RandomInt(1, 100)
+	account := createAccountWithBalance(t, util.RandomInt(amount, 1000))
+
+	result, err := store.WithdrawTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, -amount, result.Entry.Amount)
+	require.Equal(t, account.Balance-amount, result.Account.Balance)
+}
+
+func TestWithdrawTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccount(t)
+
+	_, err := store.WithdrawTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    account.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+}
+
+func TestAdjustBalanceTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountWithBalance(t, 10)
+
+	// corrections may push the balance below the overdraft limit
+	result, err := store.AdjustBalanceTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    -50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-50), result.Entry.Amount)
+	require.Equal(t, int64(-40), result.Account.Balance)
+
+	_, err = testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		Status: AccountStatusClosed,
+		ID:     account.ID,
+	})
+	require.NoError(t, err)
+
+	_, err = store.AdjustBalanceTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    40,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+}
+
+func TestSetBalanceTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountWithBalance(t, 10)
+
+	result, err := store.SetBalanceTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    25,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(15), result.Entry.Amount)
+	require.Equal(t, int64(25), result.Account.Balance)
+
+	// setting the same balance again posts nothing
+	result, err = store.SetBalanceTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    25,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Entry.ID)
+	require.Equal(t, int64(25), result.Account.Balance)
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrAccountNotEmpty is returned by CloseAccountTx when the account balance isn't zero
+var ErrAccountNotEmpty = errors.New("account balance must be zero to close it")
+
+// CloseAccountTx marks an account as closed, keeping its entries and transfers
+// The account row is locked so a concurrent transfer can't change the balance in between
+func (store *SQLStore) CloseAccountTx(ctx context.Context, accountID int64) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+
+		account, err = q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status != AccountStatusActive {
+			return ErrAccountNotActive
+		}
+
+		if account.Balance != 0 {
+			return ErrAccountNotEmpty
+		}
+
+		account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			Status: AccountStatusClosed,
+			ID:     accountID,
+		})
+		return err
+	})
+
+	return account, err
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCloseAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountWithBalance(t, 0)
+
+	closed, err := store.CloseAccountTx(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, closed.ID)
+	require.Equal(t, AccountStatusClosed, closed.Status)
+
+	// the account is kept, only its status changes
+	account2, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, account2.Status)
+
+	_, err = store.CloseAccountTx(context.Background(), account.ID)
+	require.ErrorIs(t, err, ErrAccountNotActive)
+
+	// a closed account doesn't block a new one in the same currency
+	account3, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, account3.Status)
+}
+
+func TestCloseAccountTxNotEmpty(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountWithBalance(t, 10)
+
+	_, err := store.CloseAccountTx(context.Background(), account.ID)
+	require.ErrorIs(t, err, ErrAccountNotEmpty)
+
+	account2, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, account2.Status)
+}
+
+func TestTransferTxClosedAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	a1 := createAccountWithBalance(t, 100)
+	a2 := createAccountWithBalance(t, 0)
+
+	_, err := store.CloseAccountTx(context.Background(), a2.ID)
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountNotActive)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, a1.Balance, updatedAccount1.Balance)
+}
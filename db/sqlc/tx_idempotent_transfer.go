@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/lib/pq"
+)
+
+// ErrIdempotencyKeyConflict is returned when an idempotency key is reused with a different request
+var ErrIdempotencyKeyConflict = errors.New("idempotency key was already used with a different request")
+
+// IdempotentTransferTxParams contains the input parameters of the idempotent transfer transaction
+type IdempotentTransferTxParams struct {
+	TransferTxParams
+	Username    string `json:"username"`
+	Key         string `json:"key"`
+	RequestHash string `json:"request_hash"`
+}
+
+// IdempotentTransferTx performs a money transfer at most once per (username, key) pair
+// A replay with the same request hash returns the original result, a different hash returns ErrIdempotencyKeyConflict
+func (store *SQLStore) IdempotentTransferTx(ctx context.Context, arg IdempotentTransferTxParams) (TransferTxResult, error) {
+	keyArg := GetIdempotencyKeyParams{Username: arg.Username, Key: arg.Key}
+
+	stored, err := store.GetIdempotencyKey(ctx, keyArg)
+	if err == nil {
+		return replayTransfer(stored, arg.RequestHash)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return TransferTxResult{}, err
+	}
+
+	var result TransferTxResult
+
+	err = store.execTx(ctx, txOptions(arg.TxOptions, readCommitted), func(q *Queries) error {
+		var err error
+		result, err = transfer(ctx, q, arg.TransferTxParams)
+		if err != nil {
+			return err
+		}
+
+		response, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+			Username:    arg.Username,
+			Key:         arg.Key,
+			RequestHash: arg.RequestHash,
+			Response:    response,
+		})
+		return err
+	})
+	if err != nil {
+		// a concurrent request with the same key won the race, its transfer is the one that counts
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			stored, err = store.GetIdempotencyKey(ctx, keyArg)
+			if err != nil {
+				return TransferTxResult{}, err
+			}
+
+			return replayTransfer(stored, arg.RequestHash)
+		}
+
+		return TransferTxResult{}, err
+	}
+
+	return result, nil
+}
+
+func replayTransfer(stored IdempotencyKey, requestHash string) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	if stored.RequestHash != requestHash {
+		return result, ErrIdempotencyKeyConflict
+	}
+
+	err := json.Unmarshal(stored.Response, &result)
+	return result, err
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+)
+
+func TestIdempotentTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	amount := int64(10)
+	a1 := createAccountWithBalance(t, util.RandomInt(2*amount, 1000))
+	a2 := createRandomAccount(t)
+
+	arg := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: a1.ID,
+			ToAccountID:   a2.ID,
+			Amount:        amount,
+		},
+		Username:    a1.Owner,
+		Key:         util.RandomString(16),
+		RequestHash: util.RandomString(64),
+	}
+
+	result1, err := store.IdempotentTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, result1.Transfer.ID)
+
+	// a replay returns the original transfer without moving money again
+	result2, err := store.IdempotentTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, result1.Transfer.ID, result2.Transfer.ID)
+	require.Equal(t, result1.FromEntry.ID, result2.FromEntry.ID)
+	require.Equal(t, result1.ToEntry.ID, result2.ToEntry.ID)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, a1.Balance-amount, updatedAccount1.Balance)
+
+	// the same key with a different request is rejected
+	arg.RequestHash = util.RandomString(64)
+	_, err = store.IdempotentTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+}
+
+func TestIdempotentTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	n := 5
+	amount := int64(10)
+	a1 := createAccountWithBalance(t, util.RandomInt(int64(n)*amount, 1000))
+	a2 := createRandomAccount(t)
+
+	arg := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: a1.ID,
+			ToAccountID:   a2.ID,
+			Amount:        amount,
+		},
+		Username:    a1.Owner,
+		Key:         util.RandomString(16),
+		RequestHash: util.RandomString(64),
+	}
+
+	results := make(chan TransferTxResult)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.IdempotentTransferTx(context.Background(), arg)
+
+			results <- result
+			errs <- err
+		}()
+	}
+
+	transferIDs := make(map[int64]bool)
+	for i := 0; i < n; i++ {
+		r := <-results
+		e := <-errs
+
+		require.NoError(t, e)
+		transferIDs[r.Transfer.ID] = true
+	}
+	require.Len(t, transferIDs, 1)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, a1.Balance-amount, updatedAccount1.Balance)
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"sort"
+)
+
+// ErrUnbalancedJournal is returned by PostJournal when the postings of a journal entry don't sum to zero in every currency
+var ErrUnbalancedJournal = errors.New("journal entry is not balanced")
+
+// numericValueOutOfRangeCode is what postgres fails with when a balance doesn't fit into bigint anymore
+const numericValueOutOfRangeCode = "22003"
+
+// Posting moves Amount into an account, a negative amount is a debit and a positive one a credit
+type Posting struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+	// balanceApplied records an amount the balance already includes, reconciliation uses it to catch entries up
+	balanceApplied bool
+}
+
+// PostJournalParams contains the input parameters of the journal transaction
+// TransferID links the journal entry to the transfer it carries out
+type PostJournalParams struct {
+	Kind       JournalKind   `json:"kind"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Postings   []Posting     `json:"postings"`
+	// TxOptions overrides the read committed isolation of PostJournal, the accounts are locked before they are posted to
+	TxOptions *sql.TxOptions `json:"-"`
+}
+
+// PostJournalResult is the result of the journal transaction
+// Entries and Accounts are in the same order as the postings, each account as it was right after its posting
+type PostJournalResult struct {
+	JournalEntry JournalEntry `json:"journal_entry"`
+	Entries      []Entry      `json:"entries"`
+	Accounts     []Account    `json:"accounts"`
+}
+
+// PostJournal records a journal entry and applies its postings to the account balances
+// The transaction is rolled back with ErrUnbalancedJournal unless debits equal credits in every currency
+func (store *SQLStore) PostJournal(ctx context.Context, arg PostJournalParams) (PostJournalResult, error) {
+	var result PostJournalResult
+
+	err := store.execTx(ctx, txOptions(arg.TxOptions, readCommitted), func(q *Queries) error {
+		var err error
+		result, err = postJournal(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// postJournal is the only place account balances change, using queries bound to an already open transaction
+// A balance leaving the range of bigint fails the posting with ErrBalanceOverflow
+// Balances are updated in account ID order so concurrent journal entries can't deadlock on each other
+func postJournal(ctx context.Context, q *Queries, arg PostJournalParams) (PostJournalResult, error) {
+	var result PostJournalResult
+
+	if len(arg.Postings) < 2 {
+		return result, fmt.Errorf("%w: it needs at least two postings", ErrUnbalancedJournal)
+	}
+
+	for _, posting := range arg.Postings {
+		if posting.Amount == 0 {
+			return result, fmt.Errorf("%w: posting to account %d has no amount", ErrUnbalancedJournal, posting.AccountID)
+		}
+	}
+
+	journalEntry, err := q.CreateJournalEntry(ctx, CreateJournalEntryParams{
+		Kind:       arg.Kind,
+		TransferID: arg.TransferID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	order := make([]int, len(arg.Postings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return arg.Postings[order[i]].AccountID < arg.Postings[order[j]].AccountID
+	})
+
+	accounts := make([]Account, len(arg.Postings))
+	totals := make(map[string]int64)
+	for _, i := range order {
+		posting := arg.Postings[i]
+		if posting.balanceApplied {
+			accounts[i], err = q.GetAccountForUpdate(ctx, posting.AccountID)
+		} else {
+			accounts[i], err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+				Amount: posting.Amount,
+				ID:     posting.AccountID,
+			})
+		}
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == numericValueOutOfRangeCode {
+				return result, fmt.Errorf("%w: account %d", ErrBalanceOverflow, posting.AccountID)
+			}
+			return result, err
+		}
+
+		totals[accounts[i].Currency] += posting.Amount
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			return result, fmt.Errorf("%w: %s postings sum to %d", ErrUnbalancedJournal, currency, total)
+		}
+	}
+
+	entries := make([]Entry, len(arg.Postings))
+	for i, posting := range arg.Postings {
+		entries[i], err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:      posting.AccountID,
+			Amount:         posting.Amount,
+			JournalEntryID: sql.NullInt64{Int64: journalEntry.ID, Valid: true},
+			TransferID:     arg.TransferID,
+			EntryType:      entryType(arg.Kind, posting.Amount),
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	result.JournalEntry = journalEntry
+	result.Entries = entries
+	result.Accounts = accounts
+	return result, nil
+}
+
+// entryType tells statements why a posting of a journal entry of the given kind moved money
+func entryType(kind JournalKind, amount int64) EntryType {
+	switch kind {
+	case JournalKindTransfer:
+		if amount < 0 {
+			return EntryTypeTransferDebit
+		}
+		return EntryTypeTransferCredit
+	case JournalKindDeposit:
+		return EntryTypeDeposit
+	case JournalKindWithdrawal:
+		return EntryTypeWithdrawal
+	case JournalKindFee:
+		return EntryTypeFee
+	default:
+		return EntryTypeAdjustment
+	}
+}
+
+// systemAccount returns the open system account of the given kind, opening it when the currency doesn't have one yet
+func systemAccount(ctx context.Context, q *Queries, kind AccountKind, currency string) (Account, error) {
+	arg := GetSystemAccountParams{Kind: kind, Currency: currency}
+
+	account, err := q.GetSystemAccount(ctx, arg)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return account, err
+	}
+
+	err = q.CreateSystemAccount(ctx, CreateSystemAccountParams{Currency: currency, Kind: kind})
+	if err != nil {
+		return account, err
+	}
+
+	return q.GetSystemAccount(ctx, arg)
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+)
+
+func createAccountInCurrency(t *testing.T, currency string, balance int64) Account {
+	user := createRandomUser(t)
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestPostJournal(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 100)
+	a3 := createAccountInCurrency(t, currency, 100)
+
+	result, err := store.PostJournal(context.Background(), PostJournalParams{
+		Kind: JournalKindFee,
+		Postings: []Posting{
+			{AccountID: a3.ID, Amount: -30},
+			{AccountID: a1.ID, Amount: 10},
+			{AccountID: a2.ID, Amount: 20},
+		},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, result.JournalEntry.ID)
+	require.Equal(t, JournalKindFee, result.JournalEntry.Kind)
+
+	// results follow the order of the postings, not the order the balances were updated in
+	require.Len(t, result.Entries, 3)
+	require.Equal(t, a3.ID, result.Entries[0].AccountID)
+	require.Equal(t, int64(70), result.Accounts[0].Balance)
+	require.Equal(t, int64(110), result.Accounts[1].Balance)
+	require.Equal(t, int64(120), result.Accounts[2].Balance)
+
+	postings, err := testQueries.ListPostings(context.Background(), result.JournalEntry.ID)
+	require.NoError(t, err)
+	require.Len(t, postings, 3)
+
+	var total int64
+	for _, posting := range postings {
+		total += posting.Amount
+	}
+	require.Zero(t, total)
+}
+
+func TestPostJournalUnbalanced(t *testing.T) {
+	store := NewStore(testDB)
+
+	a1 := createAccountInCurrency(t, util.USD, 100)
+	a2 := createAccountInCurrency(t, util.USD, 100)
+	a3 := createAccountInCurrency(t, util.EUR, 100)
+
+	testCases := []struct {
+		name     string
+		postings []Posting
+	}{
+		{
+			name: "DebitsExceedCredits",
+			postings: []Posting{
+				{AccountID: a1.ID, Amount: -20},
+				{AccountID: a2.ID, Amount: 10},
+			},
+		},
+		{
+			// the amounts sum to zero but each currency is off by the same amount
+			name: "MixedCurrencies",
+			postings: []Posting{
+				{AccountID: a1.ID, Amount: -10},
+				{AccountID: a3.ID, Amount: 10},
+			},
+		},
+		{
+			name: "SinglePosting",
+			postings: []Posting{
+				{AccountID: a1.ID, Amount: 10},
+			},
+		},
+		{
+			name: "ZeroAmount",
+			postings: []Posting{
+				{AccountID: a1.ID, Amount: 0},
+				{AccountID: a2.ID, Amount: 0},
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.PostJournal(context.Background(), PostJournalParams{
+				Kind:     JournalKindAdjustment,
+				Postings: tc.postings,
+			})
+			require.ErrorIs(t, err, ErrUnbalancedJournal)
+
+			for _, account := range []Account{a1, a2, a3} {
+				updated, err := testQueries.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Equal(t, account.Balance, updated.Balance)
+			}
+		})
+	}
+}
+
+func TestDepositTxPostsAgainstSuspense(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccount(t)
+	suspense, err := systemAccount(context.Background(), testQueries, AccountKindSuspense, account.Currency)
+	require.NoError(t, err)
+
+	result, err := store.DepositTx(context.Background(), BalanceTxParams{
+		AccountID: account.ID,
+		Amount:    50,
+	})
+	require.NoError(t, err)
+	require.True(t, result.Entry.JournalEntryID.Valid)
+	require.Equal(t, EntryTypeDeposit, result.Entry.EntryType)
+	require.False(t, result.Entry.TransferID.Valid)
+
+	postings, err := testQueries.ListPostings(context.Background(), result.Entry.JournalEntryID.Int64)
+	require.NoError(t, err)
+	require.Len(t, postings, 2)
+	require.Equal(t, suspense.ID, postings[1].AccountID)
+	require.Equal(t, int64(-50), postings[1].Amount)
+}
+
+func TestTransferTxSystemAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountWithBalance(t, 100)
+	fees, err := systemAccount(context.Background(), testQueries, AccountKindFees, account.Currency)
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   fees.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrSystemAccount)
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Types of the domain events written to the outbox
+const (
+	EventUserCreated       = "user.created"
+	EventAccountCreated    = "account.created"
+	EventTransferCompleted = "transfer.completed"
+)
+
+// UserCreatedEvent is the payload of EventUserCreated
+type UserCreatedEvent struct {
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountCreatedEvent is the payload of EventAccountCreated
+type AccountCreatedEvent struct {
+	AccountID int64     `json:"account_id"`
+	Owner     string    `json:"owner"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TransferCompletedEvent is the payload of EventTransferCompleted, the balances are the ones right after the transfer
+type TransferCompletedEvent struct {
+	TransferID    int64     `json:"transfer_id"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	ToAmount      int64     `json:"to_amount"`
+	ExchangeRate  float64   `json:"exchange_rate"`
+	FromCurrency  string    `json:"from_currency"`
+	ToCurrency    string    `json:"to_currency"`
+	FromBalance   int64     `json:"from_balance"`
+	ToBalance     int64     `json:"to_balance"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CreateUser creates the user and writes EventUserCreated within a single database transaction
+func (store *SQLStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+		user, err = insertUser(ctx, q, arg)
+		return err
+	})
+
+	return user, err
+}
+
+// insertUser creates the user and writes EventUserCreated using queries bound to an already open transaction
+func insertUser(ctx context.Context, q *Queries, arg CreateUserParams) (User, error) {
+	user, err := q.CreateUser(ctx, arg)
+	if err != nil {
+		return user, err
+	}
+
+	return user, writeEvent(ctx, q, EventUserCreated, user.Username, UserCreatedEvent{
+		Username:  user.Username,
+		FullName:  user.FullName,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	})
+}
+
+// CreateAccount opens the account and writes EventAccountCreated within a single database transaction
+func (store *SQLStore) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return writeEvent(ctx, q, EventAccountCreated, strconv.FormatInt(account.ID, 10), AccountCreatedEvent{
+			AccountID: account.ID,
+			Owner:     account.Owner,
+			Currency:  account.Currency,
+			CreatedAt: account.CreatedAt,
+		})
+	})
+
+	return account, err
+}
+
+// writeTransferEvent records the transfer carried out by result in the outbox
+func writeTransferEvent(ctx context.Context, q *Queries, result TransferTxResult) error {
+	transfer := result.Transfer
+	return writeEvent(ctx, q, EventTransferCompleted, strconv.FormatInt(transfer.ID, 10), TransferCompletedEvent{
+		TransferID:    transfer.ID,
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		ToAmount:      transfer.ToAmount,
+		ExchangeRate:  transfer.ExchangeRate,
+		FromCurrency:  result.FromAccount.Currency,
+		ToCurrency:    result.ToAccount.Currency,
+		FromBalance:   result.FromAccount.Balance,
+		ToBalance:     result.ToAccount.Balance,
+		CreatedAt:     transfer.CreatedAt,
+	})
+}
+
+// writeEvent adds an event to the outbox using queries bound to the transaction that made the change it describes
+func writeEvent(ctx context.Context, q *Queries, eventType string, aggregateID string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:   eventType,
+		AggregateID: aggregateID,
+		Payload:     data,
+	})
+	return err
+}
+
+// DispatchOutboxTxParams contains the input parameters of the outbox dispatch transactions
+type DispatchOutboxTxParams struct {
+	Now time.Time
+	// Limit is how many due events are claimed at most
+	Limit int32
+	// LeaseDuration is how long claimed events are kept from other dispatchers, it has to outlast publishing the batch
+	LeaseDuration time.Duration
+	// MaxAttempts is how often an event is tried before it is dead, dead events are no longer published
+	MaxAttempts int32
+	// Backoff is the wait before the next attempt of an event that failed attempts times
+	Backoff func(attempts int32) time.Duration
+	// ReleaseDelay is the wait before the events left in a batch ended by a failure are claimed again
+	ReleaseDelay time.Duration
+	// Publish delivers one event, it may be called again for an event it already delivered
+	Publish func(ctx context.Context, event Outbox) error
+}
+
+// DispatchOutboxTx publishes the oldest due events in order and marks them sent, returning how many were sent
+// The events are claimed in a short transaction that leases them for arg.LeaseDuration and are published outside of it
+// The first event that fails to publish gets its error recorded and ends the batch, it is tried again after
+// arg.Backoff while the events left are claimed again after arg.ReleaseDelay, and its error is returned
+// An event backing off or dead after failing arg.MaxAttempts times no longer holds back the ones after it
+// An event published right before it could be marked sent is published again, so delivery is at least once
+func (store *SQLStore) DispatchOutboxTx(ctx context.Context, arg DispatchOutboxTxParams) (int, error) {
+	var events []Outbox
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+		events, err = q.ListDueOutboxEventsForUpdate(ctx, ListDueOutboxEventsForUpdateParams{
+			Now:       arg.Now,
+			BatchSize: arg.Limit,
+		})
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		return q.LeaseOutboxEvents(ctx, LeaseOutboxEventsParams{
+			NextAttemptAt: arg.Now.Add(arg.LeaseDuration),
+			Ids:           outboxEventIDs(events),
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, event := range events {
+		if publishErr := arg.Publish(ctx, event); publishErr != nil {
+			return i, store.recordOutboxFailure(ctx, arg, events[i:], publishErr)
+		}
+
+		if err := store.MarkOutboxEventSent(ctx, event.ID); err != nil {
+			return i, err
+		}
+	}
+
+	return len(events), nil
+}
+
+// recordOutboxFailure records publishErr for the first of the events and backs it off,
+// the others are released after arg.ReleaseDelay, publishErr is returned
+func (store *SQLStore) recordOutboxFailure(ctx context.Context, arg DispatchOutboxTxParams, events []Outbox, publishErr error) error {
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		_, err := q.RecordOutboxEventFailure(ctx, RecordOutboxEventFailureParams{
+			ID:            events[0].ID,
+			LastError:     publishErr.Error(),
+			NextAttemptAt: arg.Now.Add(arg.Backoff(events[0].Attempts + 1)),
+			MaxAttempts:   arg.MaxAttempts,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.LeaseOutboxEvents(ctx, LeaseOutboxEventsParams{
+			NextAttemptAt: arg.Now.Add(arg.ReleaseDelay),
+			Ids:           outboxEventIDs(events[1:]),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return publishErr
+}
+
+func outboxEventIDs(events []Outbox) []int64 {
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
@@ -0,0 +1,318 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// noBackoff tries failed events again right away
+func noBackoff(int32) time.Duration {
+	return 0
+}
+
+// drainOutbox publishes every unsent event and returns the ones published
+func drainOutbox(t *testing.T, store Store) []Outbox {
+	var published []Outbox
+
+	for {
+		sent, err := store.DispatchOutboxTx(context.Background(), DispatchOutboxTxParams{
+			Now:           time.Now(),
+			Limit:         100,
+			LeaseDuration: time.Minute,
+			MaxAttempts:   3,
+			Backoff:       noBackoff,
+			Publish: func(_ context.Context, event Outbox) error {
+				published = append(published, event)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		if sent < 100 {
+			return published
+		}
+	}
+}
+
+func findEvent(t *testing.T, events []Outbox, eventType string, aggregateID string) Outbox {
+	for _, event := range events {
+		if event.EventType == eventType && event.AggregateID == aggregateID {
+			return event
+		}
+	}
+
+	require.FailNowf(t, "event not published", "%s %s", eventType, aggregateID)
+	return Outbox{}
+}
+
+func TestCreateUserWritesEvent(t *testing.T) {
+	store := NewStore(testDB)
+
+	user, err := store.CreateUser(context.Background(), CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	event := findEvent(t, drainOutbox(t, store), EventUserCreated, user.Username)
+
+	var payload UserCreatedEvent
+	require.NoError(t, json.Unmarshal(event.Payload, &payload))
+	require.Equal(t, user.Username, payload.Username)
+	require.Equal(t, user.Email, payload.Email)
+	require.NotContains(t, string(event.Payload), user.HashedPassword)
+}
+
+func TestCreateAccountWritesEvent(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	account, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	event := findEvent(t, drainOutbox(t, store), EventAccountCreated, strconv.FormatInt(account.ID, 10))
+
+	var payload AccountCreatedEvent
+	require.NoError(t, json.Unmarshal(event.Payload, &payload))
+	require.Equal(t, account.ID, payload.AccountID)
+	require.Equal(t, account.Owner, payload.Owner)
+	require.Equal(t, account.Currency, payload.Currency)
+}
+
+func TestTransferTxWritesEvent(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 0)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        40,
+	})
+	require.NoError(t, err)
+
+	event := findEvent(t, drainOutbox(t, store), EventTransferCompleted, strconv.FormatInt(result.Transfer.ID, 10))
+
+	var payload TransferCompletedEvent
+	require.NoError(t, json.Unmarshal(event.Payload, &payload))
+	require.Equal(t, result.Transfer.ID, payload.TransferID)
+	require.Equal(t, int64(40), payload.Amount)
+	require.Equal(t, int64(60), payload.FromBalance)
+	require.Equal(t, int64(40), payload.ToBalance)
+
+	// a rejected transfer is rolled back together with its event
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a2.ID,
+		ToAccountID:   a1.ID,
+		Amount:        1000,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	for _, event := range drainOutbox(t, store) {
+		require.NotEqual(t, EventTransferCompleted, event.EventType)
+	}
+}
+
+func TestDispatchOutboxTxPublishError(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	user := createRandomUser(t)
+	account, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	publishErr := errors.New("subscriber is down")
+	sent, err := store.DispatchOutboxTx(context.Background(), DispatchOutboxTxParams{
+		Now:           time.Now(),
+		Limit:         100,
+		LeaseDuration: time.Minute,
+		MaxAttempts:   3,
+		Backoff:       noBackoff,
+		Publish: func(context.Context, Outbox) error {
+			return publishErr
+		},
+	})
+	require.ErrorIs(t, err, publishErr)
+	require.Zero(t, sent)
+
+	// the failed event is published again by the next dispatch
+	event := findEvent(t, drainOutbox(t, store), EventAccountCreated, strconv.FormatInt(account.ID, 10))
+	require.Equal(t, int32(1), event.Attempts)
+	require.Equal(t, publishErr.Error(), event.LastError)
+}
+
+// createAccountEvent opens an account and returns the aggregate id of its EventAccountCreated
+func createAccountEvent(t *testing.T, store Store) string {
+	account, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: util.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	return strconv.FormatInt(account.ID, 10)
+}
+
+func TestDispatchOutboxTxDeadEvent(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	failing := createAccountEvent(t, store)
+	next := createAccountEvent(t, store)
+
+	publishErr := errors.New("subscriber rejects the event")
+	dispatch := func() ([]string, error) {
+		var published []string
+
+		_, err := store.DispatchOutboxTx(context.Background(), DispatchOutboxTxParams{
+			Now:           time.Now(),
+			Limit:         100,
+			LeaseDuration: time.Minute,
+			MaxAttempts:   2,
+			Backoff:       noBackoff,
+			Publish: func(_ context.Context, event Outbox) error {
+				if event.AggregateID == failing {
+					return publishErr
+				}
+				published = append(published, event.AggregateID)
+				return nil
+			},
+		})
+		return published, err
+	}
+
+	// without a backoff the failing event holds back the ones after it until it is dead
+	for i := 0; i < 2; i++ {
+		published, err := dispatch()
+		require.ErrorIs(t, err, publishErr)
+		require.Empty(t, published)
+	}
+
+	published, err := dispatch()
+	require.NoError(t, err)
+	require.Equal(t, []string{next}, published)
+
+	// a dead event isn't published anymore
+	published, err = dispatch()
+	require.NoError(t, err)
+	require.Empty(t, published)
+}
+
+func TestDispatchOutboxTxLeasesEvents(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	aggregateID := createAccountEvent(t, store)
+
+	var published []Outbox
+	sent, err := store.DispatchOutboxTx(context.Background(), DispatchOutboxTxParams{
+		Now:           time.Now(),
+		Limit:         100,
+		LeaseDuration: time.Minute,
+		MaxAttempts:   3,
+		Backoff:       noBackoff,
+		Publish: func(ctx context.Context, event Outbox) error {
+			// no transaction is open while publishing and another dispatcher doesn't claim the leased event
+			require.Empty(t, drainOutbox(t, store))
+
+			published = append(published, event)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	findEvent(t, published, EventAccountCreated, aggregateID)
+
+	require.Empty(t, drainOutbox(t, store))
+}
+
+// outboxNextAttemptAt reads when the event of aggregateID is due next
+func outboxNextAttemptAt(t *testing.T, aggregateID string) time.Time {
+	var nextAttemptAt time.Time
+	err := testDB.QueryRow(
+		"select next_attempt_at from outbox where event_type = $1 and aggregate_id = $2",
+		EventAccountCreated, aggregateID,
+	).Scan(&nextAttemptAt)
+	require.NoError(t, err)
+
+	return nextAttemptAt
+}
+
+func TestDispatchOutboxTxBackoff(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	failing := createAccountEvent(t, store)
+	next := createAccountEvent(t, store)
+
+	publishErr := errors.New("subscriber is down")
+	dispatch := func(now time.Time) ([]string, error) {
+		var published []string
+
+		_, err := store.DispatchOutboxTx(context.Background(), DispatchOutboxTxParams{
+			Now:           now,
+			Limit:         100,
+			LeaseDuration: time.Minute,
+			MaxAttempts:   10,
+			Backoff: func(attempts int32) time.Duration {
+				return time.Duration(1<<(attempts-1)) * time.Minute
+			},
+			ReleaseDelay: time.Second,
+			Publish: func(_ context.Context, event Outbox) error {
+				if event.AggregateID == failing {
+					return publishErr
+				}
+				published = append(published, event.AggregateID)
+				return nil
+			},
+		})
+		return published, err
+	}
+
+	now := time.Now()
+	_, err := dispatch(now)
+	require.ErrorIs(t, err, publishErr)
+
+	// the failed event backs off while the one it ended the batch before is released shortly
+	first := outboxNextAttemptAt(t, failing)
+	require.WithinDuration(t, now.Add(time.Minute), first, time.Second)
+	require.WithinDuration(t, now.Add(time.Second), outboxNextAttemptAt(t, next), time.Second)
+
+	// the backing off event doesn't hold back the released one
+	published, err := dispatch(now.Add(2 * time.Second))
+	require.NoError(t, err)
+	require.Equal(t, []string{next}, published)
+
+	// the wait grows with every failure
+	_, err = dispatch(first)
+	require.ErrorIs(t, err, publishErr)
+	second := outboxNextAttemptAt(t, failing)
+	require.WithinDuration(t, first.Add(2*time.Minute), second, time.Second)
+
+	_, err = dispatch(second)
+	require.ErrorIs(t, err, publishErr)
+	third := outboxNextAttemptAt(t, failing)
+	require.WithinDuration(t, second.Add(4*time.Minute), third, time.Second)
+	require.Greater(t, third.Sub(second), second.Sub(first))
+
+	// the event is published once its subscriber is back
+	recovered := failing
+	failing = ""
+	published, err = dispatch(third)
+	require.NoError(t, err)
+	require.Contains(t, published, recovered)
+}
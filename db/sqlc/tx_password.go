@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ChangePasswordTxParams contains the input parameters of the password change transaction
+type ChangePasswordTxParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+	// ChangedAt is compared with the issue time of tokens, so it comes from the clock that issues them
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ChangePasswordTx sets the new password and blocks the sessions of the user,
+// so neither the old access tokens nor the old refresh tokens can be used anymore
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+		user, err = changePassword(ctx, q, arg)
+		return err
+	})
+
+	return user, err
+}
+
+func changePassword(ctx context.Context, q *Queries, arg ChangePasswordTxParams) (User, error) {
+	user, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Username:          arg.Username,
+		HashedPassword:    arg.HashedPassword,
+		PasswordChangedAt: arg.ChangedAt,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	_, err = q.BlockUserSessions(ctx, user.Username)
+	return user, err
+}
+
+// CreatePasswordResetTxParams contains the input parameters of the password reset request transaction
+type CreatePasswordResetTxParams struct {
+	Email string `json:"email"`
+	// AfterCommit runs once the code is committed, it sends the reset email
+	// Its error is returned wrapped in ErrAfterCommit, the code is kept
+	AfterCommit func(user User, reset PasswordReset) error
+}
+
+// CreatePasswordResetTxResult is the result of the password reset request transaction
+type CreatePasswordResetTxResult struct {
+	User          User          `json:"user"`
+	PasswordReset PasswordReset `json:"password_reset"`
+}
+
+// CreatePasswordResetTx creates a secret code that resets the password of the user with the email,
+// sql.ErrNoRows is returned when no user has it
+// AfterCommit only runs when the transaction committed, so no code is sent that can't be used
+func (store *SQLStore) CreatePasswordResetTx(ctx context.Context, arg CreatePasswordResetTxParams) (CreatePasswordResetTxResult, error) {
+	var result CreatePasswordResetTxResult
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+		result.User, err = q.GetUserByEmail(ctx, arg.Email)
+		if err != nil {
+			return err
+		}
+
+		secretCode, err := newSecretCode()
+		if err != nil {
+			return err
+		}
+
+		result.PasswordReset, err = q.CreatePasswordReset(ctx, CreatePasswordResetParams{
+			Username:   result.User.Username,
+			SecretCode: secretCode,
+		})
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, afterCommit(arg.AfterCommit, result.User, result.PasswordReset)
+}
+
+// ResetPasswordTxParams contains the input parameters of the password reset transaction
+type ResetPasswordTxParams struct {
+	ResetID        int64     `json:"reset_id"`
+	SecretCode     string    `json:"secret_code"`
+	HashedPassword string    `json:"hashed_password"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// ResetPasswordTx uses up the reset code and changes the password of its user like ChangePasswordTx
+// The transaction is rolled back with ErrPasswordResetInvalid if the code is wrong, used or expired
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		reset, err := q.UsePasswordReset(ctx, UsePasswordResetParams{
+			ID:         arg.ResetID,
+			SecretCode: arg.SecretCode,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrPasswordResetInvalid
+			}
+			return err
+		}
+
+		user, err = changePassword(ctx, q, ChangePasswordTxParams{
+			Username:       reset.Username,
+			HashedPassword: arg.HashedPassword,
+			ChangedAt:      arg.ChangedAt,
+		})
+		return err
+	})
+
+	return user, err
+}
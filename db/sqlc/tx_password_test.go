@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+	"time"
+)
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	arg := ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: util.RandomString(32),
+		ChangedAt:      time.Now(),
+	}
+	changed, err := store.ChangePasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.HashedPassword, changed.HashedPassword)
+	require.WithinDuration(t, arg.ChangedAt, changed.PasswordChangedAt, time.Millisecond)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+}
+
+func TestCreatePasswordResetTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	var sent PasswordReset
+	result, err := store.CreatePasswordResetTx(context.Background(), CreatePasswordResetTxParams{
+		Email: user.Email,
+		AfterCommit: func(u User, reset PasswordReset) error {
+			require.Equal(t, user.Username, u.Username)
+			sent = reset
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, result.PasswordReset, sent)
+	require.Equal(t, user.Username, sent.Username)
+	require.Len(t, sent.SecretCode, 32)
+	require.True(t, sent.ExpiredAt.After(sent.CreatedAt))
+
+	// nothing is sent for an unknown email
+	_, err = store.CreatePasswordResetTx(context.Background(), CreatePasswordResetTxParams{
+		Email: util.RandomEmail(),
+		AfterCommit: func(User, PasswordReset) error {
+			require.Fail(t, "sent a reset code for an unknown email")
+			return nil
+		},
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreatePasswordResetTxSendFailed(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	mailErr := errors.New("smtp server is down")
+	result, err := store.CreatePasswordResetTx(context.Background(), CreatePasswordResetTxParams{
+		Email: user.Email,
+		AfterCommit: func(User, PasswordReset) error {
+			return mailErr
+		},
+	})
+	require.ErrorIs(t, err, ErrAfterCommit)
+	require.ErrorIs(t, err, mailErr)
+
+	// the committed code still works
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:        result.PasswordReset.ID,
+		SecretCode:     result.PasswordReset.SecretCode,
+		HashedPassword: util.RandomString(32),
+		ChangedAt:      time.Now(),
+	})
+	require.NoError(t, err)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	created, err := store.CreatePasswordResetTx(context.Background(), CreatePasswordResetTxParams{Email: user.Email})
+	require.NoError(t, err)
+	reset := created.PasswordReset
+
+	arg := ResetPasswordTxParams{
+		ResetID:        reset.ID,
+		SecretCode:     util.RandomString(32),
+		HashedPassword: util.RandomString(32),
+		ChangedAt:      time.Now(),
+	}
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrPasswordResetInvalid)
+
+	arg.SecretCode = reset.SecretCode
+	changed, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, changed.Username)
+	require.Equal(t, arg.HashedPassword, changed.HashedPassword)
+
+	// a code works only once
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrPasswordResetInvalid)
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTxRetriesExhausted is returned when a transaction kept failing with serialization failures or deadlocks
+var ErrTxRetriesExhausted = errors.New("transaction retries exhausted")
+
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+)
+
+// RetryPolicy tells how often a transaction aborted by a serialization failure or a deadlock is run again
+type RetryPolicy struct {
+	// MaxAttempts includes the first run, values below 2 disable retries
+	MaxAttempts int
+	// InitialBackoff is doubled after every attempt up to MaxBackoff, each wait is randomized by up to half of it
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy is used by NewStore
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     200 * time.Millisecond,
+}
+
+// TxStats counts the transaction retries since the store was created
+type TxStats struct {
+	SerializationFailures int64 `json:"serialization_failures"`
+	Deadlocks             int64 `json:"deadlocks"`
+	// Retries is how many times a transaction was run again
+	Retries int64 `json:"retries"`
+	// Exhausted is how many transactions failed with ErrTxRetriesExhausted
+	Exhausted int64 `json:"exhausted"`
+}
+
+type txCounters struct {
+	serializationFailures atomic.Int64
+	deadlocks             atomic.Int64
+	retries               atomic.Int64
+	exhausted             atomic.Int64
+}
+
+func (c *txCounters) stats() TxStats {
+	return TxStats{
+		SerializationFailures: c.serializationFailures.Load(),
+		Deadlocks:             c.deadlocks.Load(),
+		Retries:               c.retries.Load(),
+		Exhausted:             c.exhausted.Load(),
+	}
+}
+
+// TxStats returns the retry counters of the store
+func (store *SQLStore) TxStats() TxStats {
+	return store.counters.stats()
+}
+
+// retryTx runs attempt until it succeeds, fails with an error that isn't worth retrying or the policy gives up
+func (store *SQLStore) retryTx(ctx context.Context, attempt func() error) error {
+	backoff := store.retryPolicy.InitialBackoff
+
+	for n := 1; ; n++ {
+		err := attempt()
+
+		code, retryable := retryableTxError(err)
+		if !retryable {
+			return err
+		}
+
+		switch code {
+		case serializationFailureCode:
+			store.counters.serializationFailures.Add(1)
+		case deadlockDetectedCode:
+			store.counters.deadlocks.Add(1)
+		}
+
+		if n >= store.retryPolicy.MaxAttempts {
+			store.counters.exhausted.Add(1)
+			return fmt.Errorf("%w after %d attempts: %w", ErrTxRetriesExhausted, n, err)
+		}
+
+		log.Printf("retrying transaction after attempt %d failed with %s", n, code)
+		store.counters.retries.Add(1)
+
+		if err := sleepBackoff(ctx, backoff); err != nil {
+			return err
+		}
+
+		backoff *= 2
+		if backoff > store.retryPolicy.MaxBackoff {
+			backoff = store.retryPolicy.MaxBackoff
+		}
+	}
+}
+
+// retryableTxError tells whether err is a serialization failure or a deadlock, which a new attempt may not hit
+func retryableTxError(err error) (string, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return "", false
+	}
+
+	code := string(pqErr.Code)
+	return code, code == serializationFailureCode || code == deadlockDetectedCode
+}
+
+// sleepBackoff waits for a random duration between half and all of backoff, so conflicting transactions don't retry in lockstep
+func sleepBackoff(ctx context.Context, backoff time.Duration) error {
+	if backoff <= 0 {
+		return ctx.Err()
+	}
+
+	wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
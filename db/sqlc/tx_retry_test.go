@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newRetryStore(maxAttempts int) *SQLStore {
+	return NewStoreWithRetryPolicy(testDB, RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}).(*SQLStore)
+}
+
+func TestRetryTx(t *testing.T) {
+	serializationFailure := &pq.Error{Code: serializationFailureCode}
+	deadlock := &pq.Error{Code: deadlockDetectedCode}
+
+	testCases := []struct {
+		name          string
+		errs          []error
+		checkResponse func(t *testing.T, attempts int, stats TxStats, err error)
+	}{
+		{
+			name: "OK",
+			errs: []error{nil},
+			checkResponse: func(t *testing.T, attempts int, stats TxStats, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, attempts)
+				require.Equal(t, TxStats{}, stats)
+			},
+		},
+		{
+			name: "RetriedSerializationFailure",
+			errs: []error{serializationFailure, nil},
+			checkResponse: func(t *testing.T, attempts int, stats TxStats, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 2, attempts)
+				require.Equal(t, TxStats{SerializationFailures: 1, Retries: 1}, stats)
+			},
+		},
+		{
+			name: "RetriedDeadlock",
+			errs: []error{deadlock, serializationFailure, nil},
+			checkResponse: func(t *testing.T, attempts int, stats TxStats, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 3, attempts)
+				require.Equal(t, TxStats{SerializationFailures: 1, Deadlocks: 1, Retries: 2}, stats)
+			},
+		},
+		{
+			name: "Exhausted",
+			errs: []error{deadlock, deadlock, deadlock},
+			checkResponse: func(t *testing.T, attempts int, stats TxStats, err error) {
+				require.ErrorIs(t, err, ErrTxRetriesExhausted)
+				require.ErrorIs(t, err, deadlock)
+				require.Equal(t, 3, attempts)
+				require.Equal(t, TxStats{Deadlocks: 3, Retries: 2, Exhausted: 1}, stats)
+			},
+		},
+		{
+			name: "NotRetryable",
+			errs: []error{&pq.Error{Code: "23505"}},
+			checkResponse: func(t *testing.T, attempts int, stats TxStats, err error) {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, ErrTxRetriesExhausted)
+				require.Equal(t, 1, attempts)
+				require.Equal(t, TxStats{}, stats)
+			},
+		},
+		{
+			name: "DomainError",
+			errs: []error{ErrInsufficientFunds},
+			checkResponse: func(t *testing.T, attempts int, stats TxStats, err error) {
+				require.ErrorIs(t, err, ErrInsufficientFunds)
+				require.Equal(t, 1, attempts)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newRetryStore(3)
+
+			attempts := 0
+			err := store.retryTx(context.Background(), func() error {
+				err := tc.errs[attempts]
+				attempts++
+				return err
+			})
+
+			tc.checkResponse(t, attempts, store.TxStats(), err)
+		})
+	}
+}
+
+func TestRetryTxCancelled(t *testing.T) {
+	store := NewStoreWithRetryPolicy(testDB, RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     time.Minute,
+	}).(*SQLStore)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := store.retryTx(ctx, func() error {
+		attempts++
+		return &pq.Error{Code: serializationFailureCode}
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, attempts)
+}
+
+// TestExecTxRetriesConcurrentUpdate changes a row another serializable transaction read before it writes it,
+// the first attempt fails with a serialization failure and the retry sees the change
+func TestExecTxRetriesConcurrentUpdate(t *testing.T) {
+	store := newRetryStore(3)
+	account := createAccountWithBalance(t, 100)
+
+	attempts := 0
+	err := store.execTx(context.Background(), serializable, func(q *Queries) error {
+		attempts++
+
+		read, err := q.GetAccount(context.Background(), account.ID)
+		if err != nil {
+			return err
+		}
+
+		if attempts == 1 {
+			_, err = testDB.Exec("update accounts set balance = balance + 1 where id = $1", account.ID)
+			require.NoError(t, err)
+		}
+
+		_, err = q.db.ExecContext(context.Background(), "update accounts set balance = $2 where id = $1", account.ID, read.Balance+10)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+
+	updated, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+11, updated.Balance)
+
+	require.Equal(t, TxStats{SerializationFailures: 1, Retries: 1}, store.TxStats())
+}
+
+// TestTransferTxRetriesOpposingTransfers hammers opposing transfers in serializable transactions,
+// which abort each other until they are retried
+func TestTransferTxRetriesOpposingTransfers(t *testing.T) {
+	store := newRetryStore(50)
+
+	n := 20
+	amount := int64(10)
+	a1 := createAccountWithBalance(t, util.RandomInt(1000, 2000))
+	a2 := createAccountWithBalance(t, util.RandomInt(1000, 2000))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		arg := TransferTxParams{FromAccountID: a1.ID, ToAccountID: a2.ID, Amount: amount, TxOptions: serializable}
+		if i%2 == 1 {
+			arg.FromAccountID, arg.ToAccountID = a2.ID, a1.ID
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.TransferTx(context.Background(), arg)
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.False(t, errors.Is(err, ErrTxRetriesExhausted), "transfer gave up: %v", err)
+		require.NoError(t, err)
+	}
+
+	// as many transfers went each way, so the balances are back where they started
+	updated1, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, a1.Balance, updated1.Balance)
+
+	updated2, err := testQueries.GetAccount(context.Background(), a2.ID)
+	require.NoError(t, err)
+	require.Equal(t, a2.Balance, updated2.Balance)
+
+	stats := store.TxStats()
+	require.Positive(t, stats.Retries)
+	require.Zero(t, stats.Exhausted)
+}
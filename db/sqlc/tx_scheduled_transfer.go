@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// scheduledRetryBackoff is the wait before a run that failed unexpectedly is tried again
+	scheduledRetryBackoff = 5 * time.Minute
+	// scheduledRetryWindow is how long a one-off run that failed unexpectedly keeps being tried
+	scheduledRetryWindow = 24 * time.Hour
+)
+
+// ScheduledTransferRunResult is the result of the scheduled transfer transaction
+type ScheduledTransferRunResult struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+}
+
+// RunScheduledTransferTx executes the scheduled transfer that has been due the longest, returning sql.ErrNoRows when none is due
+// Transfers rejected by the accounts are recorded as failed runs instead of failing the transaction,
+// either way the schedule moves on to its next run or completes
+// Any other error rolls the run back, then it is recorded as failed in a transaction of its own and the schedule
+// is tried again after scheduledRetryBackoff, ErrScheduledRunFailed wraps the error once that is done
+// Rows locked by other workers are skipped, so several server instances can run it concurrently
+func (store *SQLStore) RunScheduledTransferTx(ctx context.Context, now time.Time) (ScheduledTransferRunResult, error) {
+	var result ScheduledTransferRunResult
+	var scheduledID int64
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		scheduled, err := q.GetDueScheduledTransferForUpdate(ctx, now)
+		if err != nil {
+			return err
+		}
+		scheduledID = scheduled.ID
+
+		runArg := CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			Status:              ScheduledRunStatusSucceeded,
+			ScheduledFor:        scheduled.NextRunAt,
+		}
+
+		transferID, err := runScheduledTransfer(ctx, q, scheduled)
+		if err != nil {
+			if !rejectedTransfer(err) {
+				return err
+			}
+			runArg.Status = ScheduledRunStatusFailed
+			runArg.Error = err.Error()
+		} else {
+			runArg.TransferID = sql.NullInt64{Int64: transferID, Valid: true}
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, runArg)
+		if err != nil {
+			return err
+		}
+
+		advanceArg := AdvanceScheduledTransferParams{
+			ID:        scheduled.ID,
+			LastRunAt: sql.NullTime{Time: now, Valid: true},
+			NextRunAt: scheduled.NextRunAt,
+			Status:    ScheduledTransferStatusActive,
+		}
+		if next, ok := nextRunAt(scheduled, now); ok {
+			advanceArg.NextRunAt = next
+		} else {
+			advanceArg.Status = ScheduledTransferStatusCompleted
+		}
+
+		result.ScheduledTransfer, err = q.AdvanceScheduledTransfer(ctx, advanceArg)
+		return err
+	})
+	if err == nil || scheduledID == 0 || ctx.Err() != nil {
+		return result, err
+	}
+
+	return store.recordScheduledTransferError(ctx, scheduledID, now, err)
+}
+
+// recordScheduledTransferError records the failed run and backs the schedule off, so it doesn't stay the one due
+// the longest and hold back the others
+// A run still failing when its next occurrence comes is skipped, a one-off run is given up after scheduledRetryWindow
+func (store *SQLStore) recordScheduledTransferError(ctx context.Context, id int64, now time.Time, runErr error) (ScheduledTransferRunResult, error) {
+	var result ScheduledTransferRunResult
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		scheduled, err := q.GetScheduledTransferForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			Status:              ScheduledRunStatusFailed,
+			Error:               runErr.Error(),
+			ScheduledFor:        scheduled.NextRunAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		advanceArg := AdvanceScheduledTransferParams{
+			ID:        scheduled.ID,
+			LastRunAt: sql.NullTime{Time: now, Valid: true},
+			NextRunAt: now.Add(scheduledRetryBackoff),
+			Status:    ScheduledTransferStatusActive,
+		}
+		if next, ok := nextRunAt(scheduled, now); ok {
+			if !advanceArg.NextRunAt.Before(next) {
+				advanceArg.NextRunAt = next
+			}
+		} else if !advanceArg.NextRunAt.Before(scheduled.StartAt.Add(scheduledRetryWindow)) {
+			advanceArg.NextRunAt = scheduled.NextRunAt
+			advanceArg.Status = ScheduledTransferStatusCompleted
+		}
+
+		result.ScheduledTransfer, err = q.AdvanceScheduledTransfer(ctx, advanceArg)
+		return err
+	})
+	if err != nil {
+		return result, errors.Join(runErr, err)
+	}
+
+	return result, fmt.Errorf("%w: %w", ErrScheduledRunFailed, runErr)
+}
+
+// runScheduledTransfer moves the money within a savepoint, so a rejected transfer only undoes its own writes
+func runScheduledTransfer(ctx context.Context, q *Queries, scheduled ScheduledTransfer) (int64, error) {
+	if _, err := q.db.ExecContext(ctx, "SAVEPOINT scheduled_transfer"); err != nil {
+		return 0, err
+	}
+
+	result, err := transfer(ctx, q, TransferTxParams{
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        scheduled.Amount,
+	})
+	if err != nil {
+		if rejectedTransfer(err) {
+			if _, rbErr := q.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT scheduled_transfer"); rbErr != nil {
+				return 0, rbErr
+			}
+		}
+		return 0, err
+	}
+
+	_, err = q.db.ExecContext(ctx, "RELEASE SAVEPOINT scheduled_transfer")
+	return result.Transfer.ID, err
+}
+
+// rejectedTransfer tells the errors caused by the state of the accounts from the ones worth running the transaction again for
+func rejectedTransfer(err error) bool {
+	return errors.Is(err, ErrInsufficientFunds) || errors.Is(err, ErrAccountNotActive) || errors.Is(err, ErrSystemAccount) ||
+		errors.Is(err, ErrBalanceOverflow)
+}
+
+// nextRunAt returns the first run of the schedule after now, runs missed while no worker was running are skipped
+// It returns false once a one-off transfer ran
+func nextRunAt(scheduled ScheduledTransfer, now time.Time) (time.Time, bool) {
+	if scheduled.Recurrence == RecurrenceOnce {
+		return time.Time{}, false
+	}
+
+	next := scheduled.StartAt
+	for n := 1; !next.After(now); n++ {
+		next = occurrence(scheduled.StartAt, scheduled.Recurrence, n)
+	}
+
+	return next, true
+}
+
+// occurrence returns the nth run after start, monthly runs fall on the last day of shorter months
+func occurrence(start time.Time, recurrence Recurrence, n int) time.Time {
+	switch recurrence {
+	case RecurrenceDaily:
+		return start.AddDate(0, 0, n)
+	case RecurrenceWeekly:
+		return start.AddDate(0, 0, 7*n)
+	default:
+		year, month, day := start.Date()
+		lastDay := time.Date(year, month+time.Month(n)+1, 0, 0, 0, 0, 0, start.Location()).Day()
+		if day > lastDay {
+			day = lastDay
+		}
+		return time.Date(year, month+time.Month(n), day,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	}
+}
+
+// RunScheduledTransfers executes the due scheduled transfers every interval until ctx is done
+func RunScheduledTransfers(ctx context.Context, store Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				_, err := store.RunScheduledTransferTx(ctx, time.Now())
+				if err == nil {
+					continue
+				}
+				if errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+					break
+				}
+
+				log.Println("failed to run scheduled transfer:", err)
+				// a recorded failure was backed off, the schedules due after it can still run
+				if !errors.Is(err, ErrScheduledRunFailed) {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+	"time"
+)
+
+// createDueScheduledTransfer schedules a transfer long ago, so only the runs asked for by the test are due
+func createDueScheduledTransfer(t *testing.T, from, to Account, amount int64, recurrence Recurrence) ScheduledTransfer {
+	startAt := time.Unix(util.RandomInt(0, 315532800), 0).UTC()
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         from.Owner,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		Recurrence:    recurrence,
+		StartAt:       startAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, startAt, scheduled.NextRunAt.UTC())
+	require.Equal(t, ScheduledTransferStatusActive, scheduled.Status)
+
+	return scheduled
+}
+
+func TestRunScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 0)
+
+	scheduled := createDueScheduledTransfer(t, a1, a2, 30, RecurrenceOnce)
+
+	result, err := store.RunScheduledTransferTx(context.Background(), scheduled.StartAt)
+	require.NoError(t, err)
+	require.Equal(t, scheduled.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, ScheduledTransferStatusCompleted, result.ScheduledTransfer.Status)
+	require.True(t, result.ScheduledTransfer.LastRunAt.Valid)
+
+	require.Equal(t, ScheduledRunStatusSucceeded, result.Run.Status)
+	require.True(t, result.Run.TransferID.Valid)
+	require.Empty(t, result.Run.Error)
+
+	transfer, err := testQueries.GetTransfer(context.Background(), result.Run.TransferID.Int64)
+	require.NoError(t, err)
+	require.Equal(t, int64(30), transfer.Amount)
+
+	account, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(70), account.Balance)
+
+	// a completed one-off transfer isn't due anymore
+	_, err = store.RunScheduledTransferTx(context.Background(), scheduled.StartAt)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRunScheduledTransferTxRejected(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 0)
+
+	scheduled := createDueScheduledTransfer(t, a1, a2, 1000, RecurrenceMonthly)
+
+	result, err := store.RunScheduledTransferTx(context.Background(), scheduled.StartAt)
+	require.NoError(t, err)
+
+	require.Equal(t, ScheduledRunStatusFailed, result.Run.Status)
+	require.False(t, result.Run.TransferID.Valid)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Run.Error)
+
+	// the schedule carries on with the next month
+	require.Equal(t, ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.Equal(t, occurrence(scheduled.StartAt.UTC(), RecurrenceMonthly, 1), result.ScheduledTransfer.NextRunAt.UTC())
+
+	account, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, a1.Balance, account.Balance)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               5,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+}
+
+// failTransfersFrom makes the database reject every transfer from the account, like an outage would
+func failTransfersFrom(t *testing.T, account Account) {
+	name := fmt.Sprintf("fail_transfers_from_%d", account.ID)
+
+	_, err := testDB.Exec(fmt.Sprintf(`
+create function %[1]s() returns trigger language plpgsql as $$
+begin
+    raise exception 'transfers from account %[2]d are failing';
+end
+$$;
+create trigger %[1]s before insert on transfers
+    for each row when (new.from_account_id = %[2]d) execute function %[1]s();`, name, account.ID))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, err := testDB.Exec(fmt.Sprintf("drop trigger %[1]s on transfers; drop function %[1]s();", name))
+		require.NoError(t, err)
+	})
+}
+
+func TestRunScheduledTransferTxUnexpectedError(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 0)
+	a3 := createAccountInCurrency(t, currency, 100)
+
+	startAt := time.Unix(util.RandomInt(0, 315532800), 0).UTC()
+	schedule := func(from Account, startAt time.Time) ScheduledTransfer {
+		scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+			Owner:         from.Owner,
+			FromAccountID: from.ID,
+			ToAccountID:   a2.ID,
+			Amount:        10,
+			Recurrence:    RecurrenceDaily,
+			StartAt:       startAt,
+		})
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			_, err := testQueries.CancelScheduledTransfer(context.Background(), scheduled.ID)
+			require.NoError(t, err)
+		})
+		return scheduled
+	}
+
+	failing := schedule(a1, startAt)
+	next := schedule(a3, startAt.Add(time.Second))
+	failTransfersFrom(t, a1)
+
+	now := next.StartAt
+	result, err := store.RunScheduledTransferTx(context.Background(), now)
+	require.ErrorIs(t, err, ErrScheduledRunFailed)
+	require.ErrorContains(t, err, "are failing")
+
+	require.Equal(t, failing.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, ScheduledRunStatusFailed, result.Run.Status)
+	require.False(t, result.Run.TransferID.Valid)
+	require.Contains(t, result.Run.Error, "are failing")
+
+	// the failed run is tried again after the backoff instead of holding back the schedules due after it
+	require.Equal(t, ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.Equal(t, now.Add(scheduledRetryBackoff), result.ScheduledTransfer.NextRunAt.UTC())
+
+	result, err = store.RunScheduledTransferTx(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, next.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, ScheduledRunStatusSucceeded, result.Run.Status)
+
+	account, err := testQueries.GetAccount(context.Background(), a1.ID)
+	require.NoError(t, err)
+	require.Equal(t, a1.Balance, account.Balance)
+}
+
+func TestNextRunAt(t *testing.T) {
+	start := time.Date(2024, time.January, 31, 9, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		recurrence Recurrence
+		now        time.Time
+		next       time.Time
+		ok         bool
+	}{
+		{
+			name:       "Once",
+			recurrence: RecurrenceOnce,
+			now:        start,
+			ok:         false,
+		},
+		{
+			name:       "Daily",
+			recurrence: RecurrenceDaily,
+			now:        start,
+			next:       time.Date(2024, time.February, 1, 9, 30, 0, 0, time.UTC),
+			ok:         true,
+		},
+		{
+			// runs missed while no worker was running are skipped
+			name:       "DailyAfterDowntime",
+			recurrence: RecurrenceDaily,
+			now:        time.Date(2024, time.February, 5, 12, 0, 0, 0, time.UTC),
+			next:       time.Date(2024, time.February, 6, 9, 30, 0, 0, time.UTC),
+			ok:         true,
+		},
+		{
+			name:       "Weekly",
+			recurrence: RecurrenceWeekly,
+			now:        start,
+			next:       time.Date(2024, time.February, 7, 9, 30, 0, 0, time.UTC),
+			ok:         true,
+		},
+		{
+			name:       "MonthlyShorterMonth",
+			recurrence: RecurrenceMonthly,
+			now:        start,
+			next:       time.Date(2024, time.February, 29, 9, 30, 0, 0, time.UTC),
+			ok:         true,
+		},
+		{
+			// the day of month comes from the start, not from the clamped run before
+			name:       "MonthlyAfterShorterMonth",
+			recurrence: RecurrenceMonthly,
+			now:        time.Date(2024, time.February, 29, 9, 30, 0, 0, time.UTC),
+			next:       time.Date(2024, time.March, 31, 9, 30, 0, 0, time.UTC),
+			ok:         true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			scheduled := ScheduledTransfer{Recurrence: tc.recurrence, StartAt: start, NextRunAt: start}
+
+			next, ok := nextRunAt(scheduled, tc.now)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.next, next)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// CreateUserTxParams contains the input parameters of the sign-up transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	// AfterCommit runs once the user is committed, it sends the verification email
+	// Its error is returned wrapped in ErrAfterCommit, the user is kept and can ask for the email again
+	AfterCommit func(user User, verifyEmail VerifyEmail) error
+}
+
+// CreateUserTxResult is the result of the sign-up transaction
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates the user along with a secret code that verifies the email
+// AfterCommit only runs when the transaction committed, so no code is sent for a user that doesn't exist
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+		result.User, err = insertUser(ctx, q, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = newVerifyEmail(ctx, q, result.User)
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, afterCommit(arg.AfterCommit, result.User, result.VerifyEmail)
+}
+
+// ResendVerifyEmailTxParams contains the input parameters of the transaction creating a new verification code
+type ResendVerifyEmailTxParams struct {
+	Username string `json:"username"`
+	// AfterCommit runs once the code is committed, it sends the verification email
+	AfterCommit func(user User, verifyEmail VerifyEmail) error
+}
+
+// ResendVerifyEmailTx creates a new verification code for the current email of the user,
+// ErrEmailAlreadyVerified is returned when the email is verified already
+// The user isn't locked, so it runs serializable to not send a code for an email verified meanwhile
+func (store *SQLStore) ResendVerifyEmailTx(ctx context.Context, arg ResendVerifyEmailTxParams) (VerifyEmail, error) {
+	var user User
+	var verifyEmail VerifyEmail
+
+	err := store.execTx(ctx, serializable, func(q *Queries) error {
+		var err error
+		user, err = q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		if user.IsEmailVerified {
+			return ErrEmailAlreadyVerified
+		}
+
+		verifyEmail, err = newVerifyEmail(ctx, q, user)
+		return err
+	})
+	if err != nil {
+		return verifyEmail, err
+	}
+
+	return verifyEmail, afterCommit(arg.AfterCommit, user, verifyEmail)
+}
+
+func newVerifyEmail(ctx context.Context, q *Queries, user User) (VerifyEmail, error) {
+	secretCode, err := newSecretCode()
+	if err != nil {
+		return VerifyEmail{}, err
+	}
+
+	return q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: secretCode,
+	})
+}
+
+// afterCommit runs fn if it is set and wraps its error in ErrAfterCommit
+func afterCommit[T any](fn func(user User, value T) error, user User, value T) error {
+	if fn == nil {
+		return nil
+	}
+
+	if err := fn(user, value); err != nil {
+		return fmt.Errorf("%w: %w", ErrAfterCommit, err)
+	}
+	return nil
+}
+
+// newSecretCode returns a random code that can't be guessed within the expiry of a verification email
+func newSecretCode() (string, error) {
+	code := make([]byte, 16)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(code), nil
+}
+
+// VerifyEmailTxParams contains the input parameters of the email verification transaction
+type VerifyEmailTxParams struct {
+	EmailID    int64  `json:"email_id"`
+	SecretCode string `json:"secret_code"`
+}
+
+// VerifyEmailTxResult is the result of the email verification transaction
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx uses up the verification code and marks the email of its user verified
+// The transaction is rolled back with ErrVerifyEmailInvalid if the code is wrong, used or expired,
+// or if the user changed the email since it was sent
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+		result.VerifyEmail, err = q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:         arg.EmailID,
+			SecretCode: arg.SecretCode,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrVerifyEmailInvalid
+			}
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVerifyEmailInvalid
+		}
+		return err
+	})
+
+	return result, err
+}
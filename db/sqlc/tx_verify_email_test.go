@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+)
+
+func randomCreateUserParams() CreateUserParams {
+	return CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	}
+}
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomCreateUserParams()
+
+	var sentTo VerifyEmail
+	result, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: arg,
+		AfterCommit: func(user User, verifyEmail VerifyEmail) error {
+			// the user is visible outside the transaction by the time the email goes out
+			committed, err := testQueries.GetUser(context.Background(), user.Username)
+			require.NoError(t, err)
+			require.Equal(t, arg.Email, committed.Email)
+
+			sentTo = verifyEmail
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.False(t, result.User.IsEmailVerified)
+
+	require.Equal(t, result.VerifyEmail, sentTo)
+	require.Equal(t, arg.Username, sentTo.Username)
+	require.Equal(t, arg.Email, sentTo.Email)
+	require.Len(t, sentTo.SecretCode, 32)
+	require.False(t, sentTo.IsUsed)
+	require.True(t, sentTo.ExpiredAt.After(sentTo.CreatedAt))
+}
+
+func TestCreateUserTxSendFailed(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomCreateUserParams()
+
+	mailErr := errors.New("smtp server is down")
+	result, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: arg,
+		AfterCommit: func(User, VerifyEmail) error {
+			return mailErr
+		},
+	})
+	require.ErrorIs(t, err, ErrAfterCommit)
+	require.ErrorIs(t, err, mailErr)
+	require.Equal(t, arg.Username, result.User.Username)
+
+	// the user is kept and can verify with a code sent again
+	user, err := testQueries.GetUser(context.Background(), arg.Username)
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+
+	resent, err := store.ResendVerifyEmailTx(context.Background(), ResendVerifyEmailTxParams{Username: user.Username})
+	require.NoError(t, err)
+	require.NotEqual(t, result.VerifyEmail.ID, resent.ID)
+
+	verified, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    resent.ID,
+		SecretCode: resent.SecretCode,
+	})
+	require.NoError(t, err)
+	require.True(t, verified.User.IsEmailVerified)
+
+	_, err = store.ResendVerifyEmailTx(context.Background(), ResendVerifyEmailTxParams{Username: user.Username})
+	require.ErrorIs(t, err, ErrEmailAlreadyVerified)
+}
+
+func TestCreateUserTxFailedCommitSendsNothing(t *testing.T) {
+	store := NewStore(testDB)
+	existing := createRandomUser(t)
+
+	arg := randomCreateUserParams()
+	arg.Username = existing.Username
+
+	sent := false
+	_, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: arg,
+		AfterCommit: func(User, VerifyEmail) error {
+			sent = true
+			return nil
+		},
+	})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrAfterCommit)
+	require.False(t, sent)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	created, err := store.CreateUserTx(context.Background(), CreateUserTxParams{CreateUserParams: randomCreateUserParams()})
+	require.NoError(t, err)
+	verifyEmail := created.VerifyEmail
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: util.RandomString(32),
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailInvalid)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.NoError(t, err)
+	require.True(t, result.User.IsEmailVerified)
+	require.True(t, result.VerifyEmail.IsUsed)
+
+	// a code works only once
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailInvalid)
+}
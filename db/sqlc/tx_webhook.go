@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Types of the events delivered to webhooks
+const (
+	WebhookEventTransferIn     = "transfer.in"
+	WebhookEventTransferOut    = "transfer.out"
+	WebhookEventBalanceChanged = "balance.changed"
+)
+
+// AccountActivityEvent is the payload of the webhook events of an account
+// Amount is what the account gained, negative when money left it, Balance is the one right after the change
+// TransferID and CounterpartyAccountID are only set for transfers, deposits, withdrawals and adjustments have neither
+type AccountActivityEvent struct {
+	AccountID             int64     `json:"account_id"`
+	TransferID            int64     `json:"transfer_id,omitempty"`
+	CounterpartyAccountID int64     `json:"counterparty_account_id,omitempty"`
+	Amount                int64     `json:"amount"`
+	Currency              string    `json:"currency"`
+	Balance               int64     `json:"balance"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// queueTransferWebhooks queues the transfer for the webhooks of both accounts within the transfer's transaction
+func queueTransferWebhooks(ctx context.Context, q *Queries, result TransferTxResult) error {
+	transfer := result.Transfer
+
+	from := AccountActivityEvent{
+		AccountID:             result.FromAccount.ID,
+		TransferID:            transfer.ID,
+		CounterpartyAccountID: result.ToAccount.ID,
+		Amount:                -transfer.Amount,
+		Currency:              result.FromAccount.Currency,
+		Balance:               result.FromAccount.Balance,
+		CreatedAt:             transfer.CreatedAt,
+	}
+	if err := queueWebhookDeliveries(ctx, q, from, WebhookEventTransferOut, WebhookEventBalanceChanged); err != nil {
+		return err
+	}
+
+	to := AccountActivityEvent{
+		AccountID:             result.ToAccount.ID,
+		TransferID:            transfer.ID,
+		CounterpartyAccountID: result.FromAccount.ID,
+		Amount:                transfer.ToAmount,
+		Currency:              result.ToAccount.Currency,
+		Balance:               result.ToAccount.Balance,
+		CreatedAt:             transfer.CreatedAt,
+	}
+	return queueWebhookDeliveries(ctx, q, to, WebhookEventTransferIn, WebhookEventBalanceChanged)
+}
+
+func queueWebhookDeliveries(ctx context.Context, q *Queries, event AccountActivityEvent, eventTypes ...string) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, eventType := range eventTypes {
+		_, err = q.CreateWebhookDeliveries(ctx, CreateWebhookDeliveriesParams{
+			EventType: eventType,
+			Payload:   payload,
+			AccountID: event.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeliverWebhooksTxParams contains the input parameters of the webhook delivery transactions
+type DeliverWebhooksTxParams struct {
+	Now time.Time
+	// BatchSize is how many due deliveries are claimed at most
+	BatchSize int32
+	// LeaseDuration is how long claimed deliveries are kept from other workers, it has to outlast attempting the batch
+	LeaseDuration time.Duration
+	// MaxAttempts is how often a delivery is tried before it is given up as failed
+	MaxAttempts int32
+	// Backoff is the wait before the next attempt of a delivery that failed attempts times
+	Backoff func(attempts int32) time.Duration
+	// Deliver sends the delivery to its webhook and returns the response status, 0 when no response was received
+	Deliver func(ctx context.Context, delivery ListDueWebhookDeliveriesForUpdateRow) (int32, error)
+}
+
+// DeliverWebhooksTx attempts the deliveries due at arg.Now and records the outcome of every attempt
+// A failed delivery is tried again after arg.Backoff until it failed arg.MaxAttempts times
+// The deliveries are claimed in a short transaction that leases them for arg.LeaseDuration, they are sent
+// outside of any transaction and their outcomes are recorded in a second one, so no lock is held while sending
+// Deliveries whose webhook was deleted meanwhile aren't returned
+func (store *SQLStore) DeliverWebhooksTx(ctx context.Context, arg DeliverWebhooksTxParams) ([]WebhookDelivery, error) {
+	var due []ListDueWebhookDeliveriesForUpdateRow
+
+	err := store.execTx(ctx, readCommitted, func(q *Queries) error {
+		var err error
+		due, err = q.ListDueWebhookDeliveriesForUpdate(ctx, ListDueWebhookDeliveriesForUpdateParams{
+			Now:       arg.Now,
+			BatchSize: arg.BatchSize,
+		})
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(due))
+		for i, row := range due {
+			ids[i] = row.WebhookDelivery.ID
+		}
+
+		return q.LeaseWebhookDeliveries(ctx, LeaseWebhookDeliveriesParams{
+			LeaseUntil: arg.Now.Add(arg.LeaseDuration),
+			Ids:        ids,
+		})
+	})
+	if err != nil || len(due) == 0 {
+		return nil, err
+	}
+
+	attempts := make([]RecordWebhookDeliveryAttemptParams, len(due))
+	for i, row := range due {
+		attempts[i] = webhookDeliveryAttempt(ctx, arg, row)
+	}
+
+	deliveries := make([]WebhookDelivery, 0, len(due))
+	err = store.execTx(ctx, readCommitted, func(q *Queries) error {
+		deliveries = deliveries[:0]
+		for _, attemptArg := range attempts {
+			delivery, err := q.RecordWebhookDeliveryAttempt(ctx, attemptArg)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			deliveries = append(deliveries, delivery)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// webhookDeliveryAttempt sends the delivery and returns the outcome to record
+func webhookDeliveryAttempt(ctx context.Context, arg DeliverWebhooksTxParams, row ListDueWebhookDeliveriesForUpdateRow) RecordWebhookDeliveryAttemptParams {
+	delivery := row.WebhookDelivery
+	attemptArg := RecordWebhookDeliveryAttemptParams{
+		ID:            delivery.ID,
+		Status:        WebhookDeliveryStatusSucceeded,
+		NextAttemptAt: delivery.NextAttemptAt,
+		DeliveredAt:   sql.NullTime{Time: arg.Now, Valid: true},
+	}
+
+	var err error
+	attemptArg.ResponseStatus, err = arg.Deliver(ctx, row)
+	if err == nil {
+		return attemptArg
+	}
+
+	attempts := delivery.Attempts + 1
+	attemptArg.LastError = err.Error()
+	attemptArg.DeliveredAt = sql.NullTime{}
+	if attempts >= arg.MaxAttempts {
+		attemptArg.Status = WebhookDeliveryStatusFailed
+	} else {
+		attemptArg.Status = WebhookDeliveryStatusPending
+		attemptArg.NextAttemptAt = arg.Now.Add(arg.Backoff(attempts))
+	}
+	return attemptArg
+}
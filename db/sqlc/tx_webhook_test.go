@@ -0,0 +1,280 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+	"time"
+)
+
+func createAccountWebhook(t *testing.T, account Account) Webhook {
+	hook, err := testQueries.CreateWebhook(context.Background(), CreateWebhookParams{
+		Owner:     account.Owner,
+		AccountID: account.ID,
+		Url:       "https://example.com/" + util.RandomString(8),
+		Secret:    util.RandomString(64),
+	})
+	require.NoError(t, err)
+
+	return hook
+}
+
+func listDeliveries(t *testing.T, hook Webhook) []WebhookDelivery {
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: hook.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+
+	return deliveries
+}
+
+func TestTransferTxQueuesWebhookDeliveries(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 0)
+	fromHook := createAccountWebhook(t, a1)
+	toHook := createAccountWebhook(t, a2)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        25,
+	})
+	require.NoError(t, err)
+
+	fromDeliveries := listDeliveries(t, fromHook)
+	require.Len(t, fromDeliveries, 2)
+	require.Equal(t, WebhookEventBalanceChanged, fromDeliveries[0].EventType)
+	require.Equal(t, WebhookEventTransferOut, fromDeliveries[1].EventType)
+
+	var out AccountActivityEvent
+	require.NoError(t, json.Unmarshal(fromDeliveries[1].Payload, &out))
+	require.Equal(t, result.Transfer.ID, out.TransferID)
+	require.Equal(t, a2.ID, out.CounterpartyAccountID)
+	require.Equal(t, int64(-25), out.Amount)
+	require.Equal(t, int64(75), out.Balance)
+
+	toDeliveries := listDeliveries(t, toHook)
+	require.Len(t, toDeliveries, 2)
+	require.Equal(t, WebhookEventTransferIn, toDeliveries[1].EventType)
+	require.Equal(t, WebhookDeliveryStatusPending, toDeliveries[1].Status)
+
+	var in AccountActivityEvent
+	require.NoError(t, json.Unmarshal(toDeliveries[1].Payload, &in))
+	require.Equal(t, int64(25), in.Amount)
+	require.Equal(t, int64(25), in.Balance)
+
+	// a rejected transfer queues nothing
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a2.ID,
+		ToAccountID:   a1.ID,
+		Amount:        1000,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+	require.Len(t, listDeliveries(t, toHook), 2)
+}
+
+func TestBalanceTxQueuesWebhookDeliveries(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountInCurrency(t, util.RandomCurrency(), 100)
+	hook := createAccountWebhook(t, account)
+
+	_, err := store.DepositTx(context.Background(), BalanceTxParams{AccountID: account.ID, Amount: 50})
+	require.NoError(t, err)
+	_, err = store.WithdrawTx(context.Background(), BalanceTxParams{AccountID: account.ID, Amount: 30})
+	require.NoError(t, err)
+	_, err = store.AdjustBalanceTx(context.Background(), BalanceTxParams{AccountID: account.ID, Amount: -20})
+	require.NoError(t, err)
+
+	// a rejected withdrawal queues nothing
+	_, err = store.WithdrawTx(context.Background(), BalanceTxParams{AccountID: account.ID, Amount: 1000})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	deliveries := listDeliveries(t, hook)
+	require.Len(t, deliveries, 3)
+
+	// the latest delivery comes first
+	amounts := []int64{-20, -30, 50}
+	balances := []int64{100, 120, 150}
+	for i, delivery := range deliveries {
+		require.Equal(t, WebhookEventBalanceChanged, delivery.EventType)
+
+		var event AccountActivityEvent
+		require.NoError(t, json.Unmarshal(delivery.Payload, &event))
+		require.Equal(t, account.ID, event.AccountID)
+		require.Zero(t, event.TransferID)
+		require.Equal(t, amounts[i], event.Amount)
+		require.Equal(t, balances[i], event.Balance)
+	}
+}
+
+func TestDeliverWebhooksTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 0)
+	hook := createAccountWebhook(t, a2)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// the receiver of the test webhook is down, every other due delivery succeeds
+	receiverErr := errors.New("connection refused")
+	deliver := func(now time.Time) []WebhookDelivery {
+		_, err := store.DeliverWebhooksTx(context.Background(), DeliverWebhooksTxParams{
+			Now:           now,
+			BatchSize:     1000,
+			LeaseDuration: time.Minute,
+			MaxAttempts:   2,
+			Backoff: func(attempts int32) time.Duration {
+				return time.Duration(attempts) * time.Minute
+			},
+			Deliver: func(_ context.Context, row ListDueWebhookDeliveriesForUpdateRow) (int32, error) {
+				if row.WebhookDelivery.WebhookID == hook.ID {
+					require.Equal(t, hook.Url, row.Url)
+					require.Equal(t, hook.Secret, row.Secret)
+					return 0, receiverErr
+				}
+				return 200, nil
+			},
+		})
+		require.NoError(t, err)
+
+		return listDeliveries(t, hook)
+	}
+
+	now := time.Now()
+	deliveries := deliver(now)
+	require.Len(t, deliveries, 2)
+	for _, delivery := range deliveries {
+		require.Equal(t, WebhookDeliveryStatusPending, delivery.Status)
+		require.Equal(t, int32(1), delivery.Attempts)
+		require.Equal(t, receiverErr.Error(), delivery.LastError)
+		require.WithinDuration(t, now.Add(time.Minute), delivery.NextAttemptAt, time.Second)
+	}
+
+	// nothing is due before the backoff passed
+	deliveries = deliver(now.Add(30 * time.Second))
+	require.Equal(t, int32(1), deliveries[0].Attempts)
+
+	deliveries = deliver(now.Add(time.Minute))
+	for _, delivery := range deliveries {
+		require.Equal(t, WebhookDeliveryStatusFailed, delivery.Status)
+		require.Equal(t, int32(2), delivery.Attempts)
+		require.False(t, delivery.DeliveredAt.Valid)
+	}
+}
+
+func TestDeliverWebhooksTxLeasesDeliveries(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 0)
+	hook := createAccountWebhook(t, a2)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	arg := DeliverWebhooksTxParams{
+		Now:           now,
+		BatchSize:     1000,
+		LeaseDuration: time.Minute,
+		MaxAttempts:   2,
+		Backoff: func(attempts int32) time.Duration {
+			return time.Hour
+		},
+	}
+
+	var attempted int
+	arg.Deliver = func(ctx context.Context, row ListDueWebhookDeliveriesForUpdateRow) (int32, error) {
+		if row.WebhookDelivery.WebhookID != hook.ID {
+			return 200, nil
+		}
+		attempted++
+
+		// no transaction is open while sending, the claimed deliveries can be read and updated
+		for _, delivery := range listDeliveries(t, hook) {
+			require.WithinDuration(t, now.Add(time.Minute), delivery.NextAttemptAt, time.Second)
+		}
+
+		// another worker doesn't claim the leased deliveries
+		other := arg
+		other.Deliver = func(_ context.Context, row ListDueWebhookDeliveriesForUpdateRow) (int32, error) {
+			require.NotEqual(t, hook.ID, row.WebhookDelivery.WebhookID)
+			return 200, nil
+		}
+		_, err := store.DeliverWebhooksTx(ctx, other)
+		require.NoError(t, err)
+
+		return 500, errors.New("webhook responded with status 500")
+	}
+
+	_, err = store.DeliverWebhooksTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, 2, attempted)
+
+	deliveries := listDeliveries(t, hook)
+	require.Len(t, deliveries, 2)
+	for _, delivery := range deliveries {
+		require.Equal(t, WebhookDeliveryStatusPending, delivery.Status)
+		require.Equal(t, int32(1), delivery.Attempts)
+		require.Equal(t, int32(500), delivery.ResponseStatus)
+		require.WithinDuration(t, now.Add(time.Hour), delivery.NextAttemptAt, time.Second)
+	}
+}
+
+func TestDeliverWebhooksTxDeletedWebhook(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	a1 := createAccountInCurrency(t, currency, 100)
+	a2 := createAccountInCurrency(t, currency, 0)
+	hook := createAccountWebhook(t, a2)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: a1.ID,
+		ToAccountID:   a2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// the webhook is deleted while its deliveries are being sent
+	deliveries, err := store.DeliverWebhooksTx(context.Background(), DeliverWebhooksTxParams{
+		Now:           time.Now(),
+		BatchSize:     1000,
+		LeaseDuration: time.Minute,
+		MaxAttempts:   2,
+		Backoff: func(attempts int32) time.Duration {
+			return time.Minute
+		},
+		Deliver: func(ctx context.Context, row ListDueWebhookDeliveriesForUpdateRow) (int32, error) {
+			if row.WebhookDelivery.WebhookID == hook.ID {
+				require.NoError(t, testQueries.DeleteWebhook(ctx, hook.ID))
+			}
+			return 200, nil
+		},
+	})
+	require.NoError(t, err)
+
+	for _, delivery := range deliveries {
+		require.NotEqual(t, hook.ID, delivery.WebhookID)
+	}
+}
@@ -0,0 +1,314 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: webhook.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (
+    owner, account_id, url, secret
+) VALUES (
+             $1, $2, $3, $4
+         )
+RETURNING id, owner, account_id, url, secret, created_at
+`
+
+type CreateWebhookParams struct {
+	Owner     string `json:"owner"`
+	AccountID int64  `json:"account_id"`
+	Url       string `json:"url"`
+	Secret    string `json:"secret"`
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, createWebhook,
+		arg.Owner,
+		arg.AccountID,
+		arg.Url,
+		arg.Secret,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.AccountID,
+		&i.Url,
+		&i.Secret,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebhookDeliveries = `-- name: CreateWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (
+    webhook_id, event_type, payload
+)
+select id, $1::varchar, $2::jsonb
+from webhooks
+where account_id = $3
+`
+
+type CreateWebhookDeliveriesParams struct {
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	AccountID int64           `json:"account_id"`
+}
+
+// queues the event for every webhook of the account
+func (q *Queries) CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createWebhookDeliveries, arg.EventType, arg.Payload, arg.AccountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :exec
+delete from webhooks where id = $1
+`
+
+func (q *Queries) DeleteWebhook(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhook, id)
+	return err
+}
+
+const getWebhook = `-- name: GetWebhook :one
+select id, owner, account_id, url, secret, created_at from webhooks where id = $1 limit 1
+`
+
+func (q *Queries) GetWebhook(ctx context.Context, id int64) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhook, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.AccountID,
+		&i.Url,
+		&i.Secret,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const leaseWebhookDeliveries = `-- name: LeaseWebhookDeliveries :exec
+update webhook_deliveries
+set next_attempt_at = $1
+where id = any($2::bigint[])
+`
+
+type LeaseWebhookDeliveriesParams struct {
+	LeaseUntil time.Time `json:"lease_until"`
+	Ids        []int64   `json:"ids"`
+}
+
+// moves the next attempt of claimed deliveries to the end of their lease, so no other worker claims them meanwhile
+func (q *Queries) LeaseWebhookDeliveries(ctx context.Context, arg LeaseWebhookDeliveriesParams) error {
+	_, err := q.db.ExecContext(ctx, leaseWebhookDeliveries, arg.LeaseUntil, pq.Array(arg.Ids))
+	return err
+}
+
+const listDueWebhookDeliveriesForUpdate = `-- name: ListDueWebhookDeliveriesForUpdate :many
+select webhook_deliveries.id, webhook_deliveries.webhook_id, webhook_deliveries.event_type, webhook_deliveries.payload, webhook_deliveries.status, webhook_deliveries.attempts, webhook_deliveries.next_attempt_at, webhook_deliveries.response_status, webhook_deliveries.last_error, webhook_deliveries.created_at, webhook_deliveries.delivered_at, webhooks.url, webhooks.secret
+from webhook_deliveries
+    join webhooks on webhooks.id = webhook_deliveries.webhook_id
+where webhook_deliveries.status = 'pending' and webhook_deliveries.next_attempt_at <= $1
+order by webhook_deliveries.next_attempt_at
+limit $2
+for update of webhook_deliveries skip locked
+`
+
+type ListDueWebhookDeliveriesForUpdateParams struct {
+	Now       time.Time `json:"now"`
+	BatchSize int32     `json:"batch_size"`
+}
+
+type ListDueWebhookDeliveriesForUpdateRow struct {
+	WebhookDelivery WebhookDelivery `json:"webhook_delivery"`
+	Url             string          `json:"url"`
+	Secret          string          `json:"secret"`
+}
+
+func (q *Queries) ListDueWebhookDeliveriesForUpdate(ctx context.Context, arg ListDueWebhookDeliveriesForUpdateParams) ([]ListDueWebhookDeliveriesForUpdateRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDueWebhookDeliveriesForUpdate, arg.Now, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDueWebhookDeliveriesForUpdateRow{}
+	for rows.Next() {
+		var i ListDueWebhookDeliveriesForUpdateRow
+		if err := rows.Scan(
+			&i.WebhookDelivery.ID,
+			&i.WebhookDelivery.WebhookID,
+			&i.WebhookDelivery.EventType,
+			&i.WebhookDelivery.Payload,
+			&i.WebhookDelivery.Status,
+			&i.WebhookDelivery.Attempts,
+			&i.WebhookDelivery.NextAttemptAt,
+			&i.WebhookDelivery.ResponseStatus,
+			&i.WebhookDelivery.LastError,
+			&i.WebhookDelivery.CreatedAt,
+			&i.WebhookDelivery.DeliveredAt,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+select id, webhook_id, event_type, payload, status, attempts, next_attempt_at, response_status, last_error, created_at, delivered_at from webhook_deliveries
+where webhook_id = $1
+order by id desc
+limit $2 offset $3
+`
+
+type ListWebhookDeliveriesParams struct {
+	WebhookID int64 `json:"webhook_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookDeliveries, arg.WebhookID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseStatus,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhooks = `-- name: ListWebhooks :many
+select id, owner, account_id, url, secret, created_at from webhooks
+where owner = $1
+order by id
+limit $2 offset $3
+`
+
+type ListWebhooksParams struct {
+	Owner  string `json:"owner"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListWebhooks(ctx context.Context, arg ListWebhooksParams) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhooks, arg.Owner, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Webhook{}
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.AccountID,
+			&i.Url,
+			&i.Secret,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordWebhookDeliveryAttempt = `-- name: RecordWebhookDeliveryAttempt :one
+update webhook_deliveries
+set status          = $1,
+    attempts        = attempts + 1,
+    next_attempt_at = $2,
+    response_status = $3,
+    last_error      = $4,
+    delivered_at    = $5
+where id = $6
+returning id, webhook_id, event_type, payload, status, attempts, next_attempt_at, response_status, last_error, created_at, delivered_at
+`
+
+type RecordWebhookDeliveryAttemptParams struct {
+	Status         WebhookDeliveryStatus `json:"status"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	ResponseStatus int32                 `json:"response_status"`
+	LastError      string                `json:"last_error"`
+	DeliveredAt    sql.NullTime          `json:"delivered_at"`
+	ID             int64                 `json:"id"`
+}
+
+func (q *Queries) RecordWebhookDeliveryAttempt(ctx context.Context, arg RecordWebhookDeliveryAttemptParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, recordWebhookDeliveryAttempt,
+		arg.Status,
+		arg.NextAttemptAt,
+		arg.ResponseStatus,
+		arg.LastError,
+		arg.DeliveredAt,
+		arg.ID,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
@@ -0,0 +1,40 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultMemoryPublisherCapacity is how many events the publisher created by NewPublisher keeps
+const DefaultMemoryPublisherCapacity = 1000
+
+// MemoryPublisher keeps the latest published events in the process memory, it fits tests and local development
+type MemoryPublisher struct {
+	mu       sync.RWMutex
+	capacity int
+	events   []Event
+}
+
+func (m *MemoryPublisher) Publish(_ context.Context, event Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+	if len(m.events) > m.capacity {
+		m.events = m.events[len(m.events)-m.capacity:]
+	}
+	return nil
+}
+
+// Events returns the kept events, the oldest first
+func (m *MemoryPublisher) Events() []Event {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]Event(nil), m.events...)
+}
+
+// NewMemoryPublisher creates a publisher that keeps the last capacity events
+func NewMemoryPublisher(capacity int) *MemoryPublisher {
+	return &MemoryPublisher{capacity: capacity}
+}
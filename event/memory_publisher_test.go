@@ -0,0 +1,23 @@
+package event
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMemoryPublisher(t *testing.T) {
+	publisher := NewMemoryPublisher(2)
+	require.Empty(t, publisher.Events())
+
+	for id := int64(1); id <= 3; id++ {
+		err := publisher.Publish(context.Background(), Event{ID: id})
+		require.NoError(t, err)
+	}
+
+	// only the latest events are kept
+	events := publisher.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, int64(2), events[0].ID)
+	require.Equal(t, int64(3), events[1].ID)
+}
@@ -0,0 +1,119 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"log"
+	"time"
+)
+
+// DefaultDispatchBatchSize is how many outbox events a dispatch claims at most
+const DefaultDispatchBatchSize = 100
+
+// RetryPolicy tells how often an event that failed to publish is tried again
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt, an event failing that often is dead
+	MaxAttempts int32
+	// InitialBackoff is doubled after every failed attempt up to MaxBackoff,
+	// the events left behind a failed one wait InitialBackoff before they are tried again
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns the wait after attempts failed attempts, doubling from the initial backoff up to the maximum
+func (p RetryPolicy) Backoff(attempts int32) time.Duration {
+	backoff := p.InitialBackoff
+	for n := int32(1); n < attempts && backoff < p.MaxBackoff; n++ {
+		backoff *= 2
+	}
+
+	if backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+// Event is a domain event read from the outbox
+// ID is unique per event, subscribers use it to drop the duplicates at-least-once delivery can cause
+type Event struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func newEvent(row db.Outbox) Event {
+	return Event{
+		ID:          row.ID,
+		Type:        row.EventType,
+		AggregateID: row.AggregateID,
+		Payload:     row.Payload,
+		CreatedAt:   row.CreatedAt,
+	}
+}
+
+// Publisher delivers domain events to their subscribers
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// NewPublisher creates the publisher for kind, which is either "memory" or "webhook"
+func NewPublisher(kind string, webhookURL string) (Publisher, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryPublisher(DefaultMemoryPublisherCapacity), nil
+	case "webhook":
+		if webhookURL == "" {
+			return nil, errors.New("webhook publisher needs a url")
+		}
+		return NewWebhookPublisher(webhookURL), nil
+	}
+
+	return nil, fmt.Errorf("unsupported event publisher %s", kind)
+}
+
+// Dispatch publishes the due outbox events until none is left or one fails, returning how many were sent
+// A failed event is tried again as policy says, once it failed policy.MaxAttempts times it is dead and skipped
+func Dispatch(ctx context.Context, store db.Store, publisher Publisher, batchSize int32, policy RetryPolicy) (int, error) {
+	var total int
+
+	for {
+		sent, err := store.DispatchOutboxTx(ctx, db.DispatchOutboxTxParams{
+			Now:   time.Now(),
+			Limit: batchSize,
+			// events are published one after another, each within webhookTimeout
+			LeaseDuration: time.Duration(batchSize)*webhookTimeout + time.Minute,
+			MaxAttempts:   policy.MaxAttempts,
+			Backoff:       policy.Backoff,
+			ReleaseDelay:  policy.InitialBackoff,
+			Publish: func(ctx context.Context, row db.Outbox) error {
+				return publisher.Publish(ctx, newEvent(row))
+			},
+		})
+		total += sent
+		if err != nil || sent < int(batchSize) {
+			return total, err
+		}
+	}
+}
+
+// DispatchEvents dispatches the outbox every interval until ctx is done
+func DispatchEvents(ctx context.Context, store db.Store, publisher Publisher, interval time.Duration, policy RetryPolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := Dispatch(ctx, store, publisher, DefaultDispatchBatchSize, policy); err != nil && ctx.Err() == nil {
+				log.Println("failed to dispatch events:", err)
+			}
+		}
+	}
+}
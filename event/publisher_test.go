@@ -0,0 +1,145 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vadym-98/simple_bank/db/mock"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher("memory", "")
+	require.NoError(t, err)
+	require.IsType(t, &MemoryPublisher{}, publisher)
+
+	publisher, err = NewPublisher("webhook", "http://localhost/events")
+	require.NoError(t, err)
+	require.IsType(t, &WebhookPublisher{}, publisher)
+
+	_, err = NewPublisher("webhook", "")
+	require.Error(t, err)
+
+	_, err = NewPublisher("kafka", "")
+	require.Error(t, err)
+}
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Second,
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	require.Equal(t, time.Second, testRetryPolicy.Backoff(1))
+	require.Equal(t, 2*time.Second, testRetryPolicy.Backoff(2))
+	require.Equal(t, 8*time.Second, testRetryPolicy.Backoff(4))
+	require.Equal(t, 10*time.Second, testRetryPolicy.Backoff(5))
+	require.Equal(t, 10*time.Second, testRetryPolicy.Backoff(30))
+}
+
+// publishRows stubs a dispatch that hands rows to the publisher and stops at the first failure like the store does
+func publishRows(rows ...db.Outbox) func(context.Context, db.DispatchOutboxTxParams) (int, error) {
+	return func(ctx context.Context, arg db.DispatchOutboxTxParams) (int, error) {
+		for i, row := range rows {
+			if err := arg.Publish(ctx, row); err != nil {
+				return i, err
+			}
+		}
+		return len(rows), nil
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	rows := []db.Outbox{
+		{ID: 1, EventType: db.EventUserCreated, AggregateID: "alice", Payload: []byte(`{"username":"alice"}`)},
+		{ID: 2, EventType: db.EventAccountCreated, AggregateID: "7", Payload: []byte(`{"account_id":7}`)},
+	}
+
+	testCases := []struct {
+		name       string
+		batchSize  int32
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, sent int, err error, events []Event)
+	}{
+		{
+			name:      "OK",
+			batchSize: 10,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DispatchOutboxTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.DispatchOutboxTxParams) (int, error) {
+						require.Equal(t, testRetryPolicy.MaxAttempts, arg.MaxAttempts)
+						require.Equal(t, 4*time.Second, arg.Backoff(3))
+						require.Equal(t, testRetryPolicy.InitialBackoff, arg.ReleaseDelay)
+						require.Greater(t, arg.LeaseDuration, 10*webhookTimeout)
+						require.WithinDuration(t, time.Now(), arg.Now, time.Second)
+						return publishRows(rows...)(ctx, arg)
+					})
+			},
+			check: func(t *testing.T, sent int, err error, events []Event) {
+				require.NoError(t, err)
+				require.Equal(t, 2, sent)
+				require.Len(t, events, 2)
+				require.Equal(t, int64(1), events[0].ID)
+				require.Equal(t, db.EventUserCreated, events[0].Type)
+				require.JSONEq(t, `{"username":"alice"}`, string(events[0].Payload))
+				require.Equal(t, "7", events[1].AggregateID)
+			},
+		},
+		{
+			// a full batch may leave events behind, so another one is dispatched
+			name:      "FullBatch",
+			batchSize: int32(len(rows)),
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						DispatchOutboxTx(gomock.Any(), gomock.Any()).
+						DoAndReturn(publishRows(rows...)),
+					store.EXPECT().
+						DispatchOutboxTx(gomock.Any(), gomock.Any()).
+						DoAndReturn(publishRows()),
+				)
+			},
+			check: func(t *testing.T, sent int, err error, events []Event) {
+				require.NoError(t, err)
+				require.Equal(t, 2, sent)
+			},
+		},
+		{
+			name:      "DispatchError",
+			batchSize: 10,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DispatchOutboxTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(0, errors.New("connection refused"))
+			},
+			check: func(t *testing.T, sent int, err error, events []Event) {
+				require.Error(t, err)
+				require.Zero(t, sent)
+				require.Empty(t, events)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			publisher := NewMemoryPublisher(10)
+			sent, err := Dispatch(context.Background(), store, publisher, tc.batchSize, testRetryPolicy)
+			tc.check(t, sent, err, publisher.Events())
+		})
+	}
+}
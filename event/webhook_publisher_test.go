@@ -0,0 +1,52 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookPublisher(t *testing.T) {
+	event := Event{
+		ID:          42,
+		Type:        db.EventTransferCompleted,
+		AggregateID: "9",
+		Payload:     json.RawMessage(`{"transfer_id":9}`),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+
+	var received Event
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	err := NewWebhookPublisher(server.URL).Publish(context.Background(), event)
+	require.NoError(t, err)
+
+	require.Equal(t, "42", headers.Get("X-Event-ID"))
+	require.Equal(t, db.EventTransferCompleted, headers.Get("X-Event-Type"))
+	require.Equal(t, "application/json", headers.Get("Content-Type"))
+	require.Equal(t, event.ID, received.ID)
+	require.Equal(t, event.AggregateID, received.AggregateID)
+	require.JSONEq(t, string(event.Payload), string(received.Payload))
+	require.True(t, event.CreatedAt.Equal(received.CreatedAt))
+}
+
+func TestWebhookPublisherFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewWebhookPublisher(server.URL).Publish(context.Background(), Event{ID: 1})
+	require.ErrorContains(t, err, "503")
+}
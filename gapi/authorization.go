@@ -0,0 +1,85 @@
+package gapi
+
+import (
+	"context"
+	"github.com/vadym-98/simple_bank/pb"
+	"github.com/vadym-98/simple_bank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+)
+
+// publicMethods can be called without an access token
+var publicMethods = map[string]bool{
+	pb.SimpleBank_CreateUser_FullMethodName: true,
+	pb.SimpleBank_LoginUser_FullMethodName:  true,
+}
+
+type authorizationPayloadKey struct{}
+
+// authInterceptor is the gRPC counterpart of the HTTP authMiddleware,
+// it puts the access token payload into the context of every non-public call
+func authInterceptor(maker token.Maker, revocations token.RevocationStore) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		payload, err := authorizeUser(ctx, maker, revocations)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(context.WithValue(ctx, authorizationPayloadKey{}, payload), req)
+	}
+}
+
+func authorizeUser(ctx context.Context, maker token.Maker, revocations token.RevocationStore) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+
+	values := md.Get(authorizationHeaderKey)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization header is not provided")
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) < 2 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization header format")
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization type %s", authorizationType)
+	}
+
+	payload, err := maker.VerifyToken(fields[1], token.TokenTypeAccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	revoked, err := revocations.IsRevoked(ctx, payload)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if revoked {
+		return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+	}
+
+	return payload, nil
+}
+
+// authPayload returns the payload stored by authInterceptor
+func authPayload(ctx context.Context) *token.Payload {
+	return ctx.Value(authorizationPayloadKey{}).(*token.Payload)
+}
@@ -0,0 +1,144 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/pb"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"testing"
+	"time"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	const username = "user"
+
+	testCases := []struct {
+		name          string
+		method        string
+		buildContext  func(t *testing.T, server *Server) context.Context
+		checkResponse func(t *testing.T, payload *token.Payload, err error)
+	}{
+		{
+			name:   "OK",
+			method: pb.SimpleBank_GetAccount_FullMethodName,
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return contextWithToken(t, server, username, authorizationTypeBearer, time.Minute)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.NoError(t, err)
+				require.Equal(t, username, payload.Username)
+			},
+		},
+		{
+			name:   "PublicMethod",
+			method: pb.SimpleBank_LoginUser_FullMethodName,
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.NoError(t, err)
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:   "NoAuthorization",
+			method: pb.SimpleBank_GetAccount_FullMethodName,
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return metadata.NewIncomingContext(context.Background(), metadata.MD{})
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name:   "InvalidAuthorizationFormat",
+			method: pb.SimpleBank_GetAccount_FullMethodName,
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				md := metadata.Pairs(authorizationHeaderKey, "token")
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name:   "UnsupportedAuthorizationType",
+			method: pb.SimpleBank_GetAccount_FullMethodName,
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return contextWithToken(t, server, username, "basic", time.Minute)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name:   "ExpiredToken",
+			method: pb.SimpleBank_GetAccount_FullMethodName,
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return contextWithToken(t, server, username, authorizationTypeBearer, -time.Minute)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name:   "RefreshToken",
+			method: pb.SimpleBank_GetAccount_FullMethodName,
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				tkn, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, token.TokenTypeRefreshToken, time.Minute)
+				require.NoError(t, err)
+
+				md := metadata.Pairs(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tkn))
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name:   "RevokedToken",
+			method: pb.SimpleBank_GetAccount_FullMethodName,
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				tkn, payload, err := server.tokenMaker.CreateToken(username, util.DepositorRole, token.TokenTypeAccessToken, time.Minute)
+				require.NoError(t, err)
+				require.NoError(t, server.revocations.Revoke(context.Background(), payload))
+
+				md := metadata.Pairs(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tkn))
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+			interceptor := authInterceptor(server.tokenMaker, server.revocations)
+
+			var payload *token.Payload
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				payload, _ = ctx.Value(authorizationPayloadKey{}).(*token.Payload)
+				return nil, nil
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: tc.method}
+			_, err := interceptor(tc.buildContext(t, server), nil, info, handler)
+			tc.checkResponse(t, payload, err)
+		})
+	}
+}
+
+func contextWithToken(t *testing.T, server *Server, username string, authorizationType string, duration time.Duration) context.Context {
+	tkn, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, token.TokenTypeAccessToken, duration)
+	require.NoError(t, err)
+
+	md := metadata.Pairs(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationType, tkn))
+	return metadata.NewIncomingContext(context.Background(), md)
+}
@@ -0,0 +1,49 @@
+package gapi
+
+import (
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/pb"
+	"github.com/vadym-98/simple_bank/util"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
+		CreatedAt:         timestamppb.New(user.CreatedAt),
+	}
+}
+
+func convertMoney(amount int64, currency util.Currency) *pb.Money {
+	return &pb.Money{
+		Amount:   util.NewMoney(amount, currency).String(),
+		Currency: currency.Code,
+	}
+}
+
+func convertAccount(account db.Account, currency util.Currency) *pb.Account {
+	return &pb.Account{
+		Id:             account.ID,
+		Owner:          account.Owner,
+		Balance:        convertMoney(account.Balance, currency),
+		Currency:       account.Currency,
+		CreatedAt:      timestamppb.New(account.CreatedAt),
+		OverdraftLimit: convertMoney(account.OverdraftLimit, currency),
+		Status:         string(account.Status),
+	}
+}
+
+func convertTransfer(transfer db.Transfer, from util.Currency, to util.Currency) *pb.Transfer {
+	return &pb.Transfer{
+		Id:            transfer.ID,
+		FromAccountId: transfer.FromAccountID,
+		ToAccountId:   transfer.ToAccountID,
+		Amount:        convertMoney(transfer.Amount, from),
+		ToAmount:      convertMoney(transfer.ToAmount, to),
+		ExchangeRate:  transfer.ExchangeRate,
+		CreatedAt:     timestamppb.New(transfer.CreatedAt),
+	}
+}
@@ -0,0 +1,71 @@
+package gapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+var testCurrencies = []util.Currency{
+	{Code: util.USD, Exponent: 2, Enabled: true},
+	{Code: util.EUR, Exponent: 2, Enabled: true},
+	{Code: util.CAD, Exponent: 2, Enabled: true},
+	{Code: "JPY", Exponent: 0, Enabled: true},
+	{Code: "GBP", Exponent: 2, Enabled: false},
+}
+
+// testCurrency returns one of testCurrencies by its code
+func testCurrency(code string) util.Currency {
+	for _, currency := range testCurrencies {
+		if currency.Code == code {
+			return currency
+		}
+	}
+
+	return util.Currency{}
+}
+
+// testPasswordChanges stands in for the users table behind the revocation store,
+// so the auth checks stay off the mocked store
+type testPasswordChanges map[string]time.Time
+
+func (p testPasswordChanges) GetUserPasswordChangedAt(_ context.Context, username string) (time.Time, error) {
+	return p[username], nil
+}
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	cfg := util.Config{
+		TokenSymmetricKey:    util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}
+
+	server, err := NewServer(cfg, store, token.NewMemoryRevocationStore(testPasswordChanges{}), mail.NewMemoryMailer(10))
+	require.NoError(t, err)
+
+	server.fx = util.NewStaticFXRateProvider(map[string]float64{util.USD: 1, util.EUR: 0.5, "JPY": 250})
+	server.currencies = util.NewCurrencyRegistry(func(ctx context.Context) ([]util.Currency, error) {
+		return testCurrencies, nil
+	}, 0)
+
+	return server
+}
+
+// authContext returns a context as authInterceptor leaves it for username
+func authContext(username string) context.Context {
+	payload := &token.Payload{Username: username, Role: util.DepositorRole}
+	return context.WithValue(context.Background(), authorizationPayloadKey{}, payload)
+}
+
+func requireStatusCode(t *testing.T, err error, code codes.Code) {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, code, st.Code())
+}
@@ -0,0 +1,31 @@
+package gapi
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const userAgentHeader = "user-agent"
+
+// clientMetadata describes the client a session is created for
+type clientMetadata struct {
+	UserAgent string
+	ClientIP  string
+}
+
+func extractMetadata(ctx context.Context) clientMetadata {
+	var mtdt clientMetadata
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgents := md.Get(userAgentHeader); len(userAgents) > 0 {
+			mtdt.UserAgent = userAgents[0]
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		mtdt.ClientIP = p.Addr.String()
+	}
+
+	return mtdt
+}
@@ -0,0 +1,106 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	currency, err := s.supportedCurrency(ctx, req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	arg := db.CreateAccountParams{
+		Owner:    authPayload(ctx).Username,
+		Currency: currency.Code,
+	}
+
+	account, err := s.store.CreateAccount(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				return nil, status.Errorf(codes.AlreadyExists, "account already exists: %s", err)
+			case "foreign_key_violation":
+				return nil, status.Errorf(codes.FailedPrecondition, "cannot create account: %s", err)
+			}
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create account: %s", err)
+	}
+
+	return &pb.CreateAccountResponse{Account: convertAccount(account, currency)}, nil
+}
+
+func (s *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	if err := validateID("id", req.GetId()); err != nil {
+		return nil, invalidArgumentError(err)
+	}
+
+	account, err := s.findAccount(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if account.Owner != authPayload(ctx).Username {
+		return nil, status.Error(codes.PermissionDenied, "account doesn't belong to the authenticated user")
+	}
+
+	currency, err := s.currency(ctx, account.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetAccountResponse{Account: convertAccount(account, currency)}, nil
+}
+
+func (s *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	if req.GetPageId() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "page_id must be positive")
+	}
+
+	if req.GetPageSize() < 5 || req.GetPageSize() > 10 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must be between 5 and 10")
+	}
+
+	arg := db.ListAccountsParams{
+		Owner:  authPayload(ctx).Username,
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	}
+
+	accounts, err := s.store.ListAccounts(ctx, arg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list accounts: %s", err)
+	}
+
+	rsp := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, len(accounts))}
+	for i, account := range accounts {
+		currency, err := s.currency(ctx, account.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		rsp.Accounts[i] = convertAccount(account, currency)
+	}
+
+	return rsp, nil
+}
+
+func (s *Server) findAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, status.Errorf(codes.NotFound, "account [%d] not found", accountID)
+		}
+		return account, status.Errorf(codes.Internal, "failed to get account: %s", err)
+	}
+
+	return account, nil
+}
@@ -0,0 +1,140 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/pb"
+	"github.com/vadym-98/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	if err := validateID("from_account_id", req.GetFromAccountId()); err != nil {
+		return nil, invalidArgumentError(err)
+	}
+
+	if err := validateID("to_account_id", req.GetToAccountId()); err != nil {
+		return nil, invalidArgumentError(err)
+	}
+
+	if err := s.requireVerifiedEmail(ctx); err != nil {
+		return nil, err
+	}
+
+	from, err := s.supportedCurrency(ctx, req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parsePositiveAmount(req.GetAmount(), from)
+	if err != nil {
+		return nil, err
+	}
+
+	fromAccount, err := s.findAccount(ctx, req.GetFromAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	if fromAccount.Currency != from.Code {
+		return nil, status.Errorf(codes.InvalidArgument, "account [%d] currency mismatch: %s vs %s", fromAccount.ID, fromAccount.Currency, from.Code)
+	}
+
+	if fromAccount.Owner != authPayload(ctx).Username {
+		return nil, status.Error(codes.PermissionDenied, "from account doesn't belong to authenticated user")
+	}
+
+	toAccount, err := s.findAccount(ctx, req.GetToAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	to := from
+	arg := db.TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        amount.Amount,
+	}
+
+	if toAccount.Currency != fromAccount.Currency {
+		if to, err = s.currency(ctx, toAccount.Currency); err != nil {
+			return nil, err
+		}
+
+		quote, err := util.QuoteFX(ctx, s.fx, amount, to)
+		if err != nil {
+			if errors.Is(err, util.ErrUnsupportedCurrencyPair) || errors.Is(err, util.ErrMoneyOverflow) ||
+				errors.Is(err, util.ErrConversionTooSmall) {
+				return nil, invalidArgumentError(err)
+			}
+			return nil, status.Errorf(codes.Internal, "failed to quote exchange rate: %s", err)
+		}
+
+		arg.ToAmount = quote.ConvertedAmount.Amount
+		arg.ExchangeRate = quote.Rate
+	}
+
+	result, err := s.store.TransferTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrAccountNotActive) ||
+			errors.Is(err, db.ErrSystemAccount) || errors.Is(err, db.ErrBalanceOverflow) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		if errors.Is(err, db.ErrTxRetriesExhausted) {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to transfer: %s", err)
+	}
+
+	rsp := &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer, from, to),
+		FromAccount: convertAccount(result.FromAccount, from),
+		ToAccount:   convertAccount(result.ToAccount, to),
+	}
+	return rsp, nil
+}
+
+// GetTransfer returns the transfer if the authenticated user owns either of its accounts
+func (s *Server) GetTransfer(ctx context.Context, req *pb.GetTransferRequest) (*pb.GetTransferResponse, error) {
+	if err := validateID("id", req.GetId()); err != nil {
+		return nil, invalidArgumentError(err)
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, req.GetId())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "transfer not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get transfer: %s", err)
+	}
+
+	fromAccount, err := s.findAccount(ctx, transfer.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	toAccount, err := s.findAccount(ctx, transfer.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	username := authPayload(ctx).Username
+	if fromAccount.Owner != username && toAccount.Owner != username {
+		return nil, status.Error(codes.PermissionDenied, "transfer doesn't belong to the authenticated user")
+	}
+
+	from, err := s.currency(ctx, fromAccount.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.currency(ctx, toAccount.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetTransferResponse{Transfer: convertTransfer(transfer, from, to)}, nil
+}
@@ -0,0 +1,161 @@
+package gapi
+
+import (
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vadym-98/simple_bank/db/mock"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/pb"
+	"github.com/vadym-98/simple_bank/util"
+	"github.com/vadym-98/simple_bank/util/faker"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestCreateTransferRPC(t *testing.T) {
+	u1 := faker.NewUser().Get()
+	u2 := faker.NewUser().Get()
+	a1 := faker.NewAccount().WithOwner(u1.Username).WithCurrency(util.USD).Get()
+	a2 := faker.NewAccount().WithOwner(u2.Username).WithCurrency(util.USD).Get()
+	a3 := faker.NewAccount().WithOwner(u2.Username).WithCurrency(util.EUR).Get()
+	for a3.ID == a1.ID {
+		a3.ID++
+	}
+	a4 := faker.NewAccount().WithOwner(u1.Username).WithCurrency("JPY").Get()
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateTransferRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.CreateTransferResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.CreateTransferRequest{FromAccountId: a1.ID, ToAccountId: a2.ID, Amount: "10.50", Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.TransferTxParams{FromAccountID: a1.ID, ToAccountID: a2.ID, Amount: 1050}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, rsp.GetTransfer())
+			},
+		},
+		{
+			name: "CrossCurrency",
+			req:  &pb.CreateTransferRequest{FromAccountId: a1.ID, ToAccountId: a3.ID, Amount: "10", Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.TransferTxParams{FromAccountID: a1.ID, ToAccountID: a3.ID, Amount: 1000, ToAmount: 500, ExchangeRate: 0.5}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a3.ID)).Times(1).Return(a3, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// a yen is worth 0.4 cents
+			name: "ConvertsToZero",
+			req:  &pb.CreateTransferRequest{FromAccountId: a4.ID, ToAccountId: a2.ID, Amount: "1", Currency: "JPY"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a4.ID)).Times(1).Return(a4, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "InvalidAmount",
+			req:  &pb.CreateTransferRequest{FromAccountId: a1.ID, ToAccountId: a2.ID, Amount: "1.234", Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "UnsupportedCurrency",
+			req:  &pb.CreateTransferRequest{FromAccountId: a1.ID, ToAccountId: a2.ID, Amount: "10", Currency: "GBP"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "FromAccountCurrencyMismatch",
+			req:  &pb.CreateTransferRequest{FromAccountId: a1.ID, ToAccountId: a2.ID, Amount: "10", Currency: util.EUR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			req:  &pb.CreateTransferRequest{FromAccountId: a2.ID, ToAccountId: a1.ID, Amount: "10", Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.PermissionDenied)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			req:  &pb.CreateTransferRequest{FromAccountId: a1.ID, ToAccountId: a2.ID, Amount: "10", Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a1.ID)).Times(1).Return(a1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(a2.ID)).Times(1).Return(a2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rsp, err := server.CreateTransfer(authContext(u1.Username), tc.req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
+
+func TestCreateTransferRPCRequireVerifiedEmail(t *testing.T) {
+	user := faker.NewUser().Get()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	server.config.RequireVerifiedEmail = true
+
+	req := &pb.CreateTransferRequest{FromAccountId: 1, ToAccountId: 2, Amount: "10", Currency: util.USD}
+	_, err := server.CreateTransfer(authContext(user.Username), req)
+	requireStatusCode(t, err, codes.PermissionDenied)
+}
@@ -0,0 +1,145 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/pb"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if err := validateCreateUserRequest(req); err != nil {
+		return nil, invalidArgumentError(err)
+	}
+
+	hashedPwd, err := util.HashPassword(req.GetPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+	}
+
+	arg := db.CreateUserParams{
+		Username:       req.GetUsername(),
+		HashedPassword: hashedPwd,
+		FullName:       req.GetFullName(),
+		Email:          req.GetEmail(),
+	}
+
+	result, err := s.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: arg,
+		// a lost email can be sent again through the HTTP API
+		AfterCommit: func(user db.User, verifyEmail db.VerifyEmail) error {
+			s.sendInBackground(mail.VerifyEmailMessage(s.config.VerifyEmailURL, user, verifyEmail))
+			return nil
+		},
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				return nil, status.Errorf(codes.AlreadyExists, "user already exists: %s", err)
+			}
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create user: %s", err)
+	}
+
+	return &pb.CreateUserResponse{User: convertUser(result.User)}, nil
+}
+
+func validateCreateUserRequest(req *pb.CreateUserRequest) error {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		return err
+	}
+
+	if err := validatePassword(req.GetPassword()); err != nil {
+		return err
+	}
+
+	if req.GetFullName() == "" {
+		return errors.New("full name is required")
+	}
+
+	return validateEmail(req.GetEmail())
+}
+
+func (s *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		return nil, invalidArgumentError(err)
+	}
+
+	if err := validatePassword(req.GetPassword()); err != nil {
+		return nil, invalidArgumentError(err)
+	}
+
+	user, err := s.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to find user: %s", err)
+	}
+
+	if err := util.CheckPassword(req.GetPassword(), user.HashedPassword); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "incorrect password")
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccessToken, s.config.AccessTokenDuration)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create access token: %s", err)
+	}
+
+	refreshToken, refreshPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefreshToken, s.config.RefreshTokenDuration)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create refresh token: %s", err)
+	}
+
+	mtdt := extractMetadata(ctx)
+	session, err := s.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    mtdt.UserAgent,
+		ClientIp:     mtdt.ClientIP,
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create session: %s", err)
+	}
+
+	rsp := &pb.LoginUserResponse{
+		User:                  convertUser(user),
+		SessionId:             session.ID.String(),
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  timestamppb.New(accessPayload.ExpiredAt),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: timestamppb.New(refreshPayload.ExpiredAt),
+	}
+	return rsp, nil
+}
+
+// requireVerifiedEmail fails with PermissionDenied unless the authenticated user verified the email,
+// it passes everyone when the config doesn't require verification
+func (s *Server) requireVerifiedEmail(ctx context.Context) error {
+	if !s.config.RequireVerifiedEmail {
+		return nil
+	}
+
+	user, err := s.store.GetUser(ctx, authPayload(ctx).Username)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if !user.IsEmailVerified {
+		return status.Error(codes.PermissionDenied, "email address is not verified")
+	}
+
+	return nil
+}
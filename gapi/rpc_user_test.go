@@ -0,0 +1,205 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vadym-98/simple_bank/db/mock"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/pb"
+	"github.com/vadym-98/simple_bank/util"
+	"github.com/vadym-98/simple_bank/util/faker"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type eqCreateUserTxParamsMatcher struct {
+	arg      db.CreateUserParams
+	password string
+}
+
+func (e eqCreateUserTxParamsMatcher) Matches(x any) bool {
+	txArg, ok := x.(db.CreateUserTxParams)
+	if !ok {
+		return false
+	}
+	arg := txArg.CreateUserParams
+
+	if err := util.CheckPassword(e.password, arg.HashedPassword); err != nil {
+		return false
+	}
+
+	e.arg.HashedPassword = arg.HashedPassword
+	return reflect.DeepEqual(e.arg, arg)
+}
+
+func (e eqCreateUserTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
+}
+
+func EqCreateUserTxParams(arg db.CreateUserParams, pwd string) gomock.Matcher {
+	return eqCreateUserTxParamsMatcher{arg: arg, password: pwd}
+}
+
+// failingMailer fails every send like an unreachable smtp server
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mail.Message) error {
+	return errors.New("smtp server is down")
+}
+
+func TestCreateUserRPC(t *testing.T) {
+	const pwd = "mysecret"
+	user := faker.NewUser().Get()
+	arg := db.CreateUserParams{
+		Username: user.Username,
+		FullName: user.FullName,
+		Email:    user.Email,
+	}
+	verifyEmail := db.VerifyEmail{
+		ID:         util.RandomInt(1, 1000),
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		ExpiredAt:  time.Now().Add(15 * time.Minute),
+	}
+	req := &pb.CreateUserRequest{
+		Username: user.Username,
+		Password: pwd,
+		FullName: user.FullName,
+		Email:    user.Email,
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		mailer        mail.Mailer
+		checkResponse func(t *testing.T, rsp *pb.CreateUserResponse, err error)
+		checkMailer   func(t *testing.T, mailer *mail.MemoryMailer)
+	}{
+		{
+			name: "OK",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, pwd)).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						result := db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}
+						return result, arg.AfterCommit(result.User, result.VerifyEmail)
+					})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+				require.Equal(t, user.Email, rsp.GetUser().GetEmail())
+			},
+			checkMailer: func(t *testing.T, mailer *mail.MemoryMailer) {
+				sent := mailer.Sent()
+				require.Len(t, sent, 1)
+				require.Equal(t, []string{user.Email}, sent[0].To)
+				require.Contains(t, sent[0].Body, verifyEmail.SecretCode)
+			},
+		},
+		{
+			name: "SendEmailFailed",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						result := db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}
+						return result, arg.AfterCommit(result.User, result.VerifyEmail)
+					})
+			},
+			mailer: failingMailer{},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+			},
+		},
+		{
+			name: "InternalError",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateUserTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				requireStatusCode(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateUserTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				requireStatusCode(t, err, codes.AlreadyExists)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			req:  &pb.CreateUserRequest{Username: "invalid-user#1", Password: pwd, FullName: user.FullName, Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			req:  &pb.CreateUserRequest{Username: user.Username, Password: pwd, FullName: user.FullName, Email: "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "TooShortPassword",
+			req:  &pb.CreateUserRequest{Username: user.Username, Password: "123", FullName: user.FullName, Email: user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			if tc.mailer != nil {
+				server.mailer = tc.mailer
+			}
+
+			rsp, err := server.CreateUser(context.Background(), tc.req)
+			tc.checkResponse(t, rsp, err)
+
+			// the email goes out after the response
+			server.pendingMails.Wait()
+			if tc.checkMailer != nil {
+				tc.checkMailer(t, server.mailer.(*mail.MemoryMailer))
+			}
+		})
+	}
+}
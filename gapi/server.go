@@ -0,0 +1,125 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/pb"
+	"github.com/vadym-98/simple_bank/token"
+	"github.com/vadym-98/simple_bank/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"log"
+	"net"
+	"sync"
+)
+
+// Server serves gRPC requests for banking service
+type Server struct {
+	pb.UnimplementedSimpleBankServer
+	config      util.Config
+	store       db.Store
+	tokenMaker  token.Maker
+	revocations token.RevocationStore
+	mailer      mail.Mailer
+	fx          util.FXRateProvider
+	currencies  *util.CurrencyRegistry
+	grpcServer  *grpc.Server
+	// mailCtx is the context of the emails sent in the background, it outlives the calls that queued them
+	mailCtx context.Context
+	// cancelMails aborts the emails still being sent once the drain timeout is over
+	cancelMails  context.CancelFunc
+	pendingMails sync.WaitGroup
+}
+
+// NewServer creates the gRPC server, revocations and mailer are shared with the other servers of the process
+func NewServer(cfg util.Config, store db.Store, revocations token.RevocationStore, mailer mail.Mailer) (*Server, error) {
+	tokenMaker, err := token.NewPasetoMaker(cfg.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	fx, err := util.NewFXRateProvider(cfg.FXRatesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	mailCtx, cancelMails := context.WithCancel(context.Background())
+
+	server := &Server{
+		config:      cfg,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		revocations: revocations,
+		mailer:      mailer,
+		fx:          fx,
+		currencies:  util.NewCurrencyRegistry(db.LoadCurrencies(store), cfg.CurrencyCacheTTL),
+		mailCtx:     mailCtx,
+		cancelMails: cancelMails,
+	}
+
+	server.grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		timeoutInterceptor(cfg.RequestTimeout),
+		authInterceptor(tokenMaker, revocations),
+	))
+	pb.RegisterSimpleBankServer(server.grpcServer, server)
+	// lets clients like grpcurl and evans discover the service
+	reflection.Register(server.grpcServer)
+
+	return server, nil
+}
+
+// Start serves calls on address until Shutdown is called
+func (s *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot create listener: %w", err)
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve serves calls on listener until Shutdown is called
+func (s *Server) Serve(listener net.Listener) error {
+	if err := s.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+
+	return nil
+}
+
+// sendInBackground sends msg without holding up the response, failures are only logged
+func (s *Server) sendInBackground(msg mail.Message) {
+	s.pendingMails.Add(1)
+	go func() {
+		defer s.pendingMails.Done()
+
+		if err := s.mailer.Send(s.mailCtx, msg); err != nil {
+			log.Println("failed to send email:", err)
+		}
+	}()
+}
+
+// Shutdown stops accepting calls and waits for the running ones until ctx is done,
+// then cancels them and closes the remaining connections
+func (s *Server) Shutdown(ctx context.Context) error {
+	defer s.cancelMails()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		// emails still being sent get the rest of the drain timeout
+		s.pendingMails.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,22 @@
+package gapi
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// timeoutInterceptor is the gRPC counterpart of the HTTP timeoutMiddleware,
+// it keeps the deadline set by the client when that one is sooner
+func timeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
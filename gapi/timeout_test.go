@@ -0,0 +1,69 @@
+package gapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"testing"
+	"time"
+)
+
+func TestTimeoutInterceptor(t *testing.T) {
+	testCases := []struct {
+		name         string
+		timeout      time.Duration
+		buildContext func() (context.Context, context.CancelFunc)
+		checkContext func(t *testing.T, ctx context.Context)
+	}{
+		{
+			name:    "Deadline",
+			timeout: time.Minute,
+			buildContext: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			checkContext: func(t *testing.T, ctx context.Context) {
+				deadline, ok := ctx.Deadline()
+				require.True(t, ok)
+				require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+			},
+		},
+		{
+			name:    "SoonerClientDeadline",
+			timeout: time.Minute,
+			buildContext: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Second)
+			},
+			checkContext: func(t *testing.T, ctx context.Context) {
+				deadline, ok := ctx.Deadline()
+				require.True(t, ok)
+				require.WithinDuration(t, time.Now().Add(time.Second), deadline, time.Second)
+			},
+		},
+		{
+			name:    "Disabled",
+			timeout: 0,
+			buildContext: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			checkContext: func(t *testing.T, ctx context.Context) {
+				_, ok := ctx.Deadline()
+				require.False(t, ok)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := tc.buildContext()
+			defer cancel()
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				tc.checkContext(t, ctx)
+				return nil, nil
+			}
+
+			_, err := timeoutInterceptor(tc.timeout)(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/vadym-98/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/mail"
+)
+
+// the rules follow the binding tags of the HTTP requests
+
+func validateUsername(value string) error {
+	if value == "" {
+		return errors.New("username is required")
+	}
+
+	for _, r := range value {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return errors.New("username must contain only letters and digits")
+		}
+	}
+
+	return nil
+}
+
+func validatePassword(value string) error {
+	if len(value) < 6 {
+		return errors.New("password must contain at least 6 characters")
+	}
+
+	return nil
+}
+
+func validateEmail(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("email is not valid: %w", err)
+	}
+
+	return nil
+}
+
+func validateID(field string, value int64) error {
+	if value < 1 {
+		return fmt.Errorf("%s must be positive", field)
+	}
+
+	return nil
+}
+
+func invalidArgumentError(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// supportedCurrency looks up a currency that new accounts and transfers may use
+func (s *Server) supportedCurrency(ctx context.Context, code string) (util.Currency, error) {
+	if !s.currencies.IsSupported(ctx, code) {
+		return util.Currency{}, status.Errorf(codes.InvalidArgument, "unsupported currency %s", code)
+	}
+
+	return s.currency(ctx, code)
+}
+
+// currency looks up a currency already used by an account or accepted by supportedCurrency
+func (s *Server) currency(ctx context.Context, code string) (util.Currency, error) {
+	currency, ok := s.currencies.Get(ctx, code)
+	if !ok {
+		return currency, status.Errorf(codes.Internal, "unknown currency %s", code)
+	}
+
+	return currency, nil
+}
+
+// parsePositiveAmount reads a decimal amount like "12.34" that must be greater than zero
+func parsePositiveAmount(value string, currency util.Currency) (util.Money, error) {
+	amount, err := util.ParseMoney(value, currency)
+	if err != nil {
+		return amount, invalidArgumentError(err)
+	}
+
+	if amount.Amount <= 0 {
+		return amount, status.Error(codes.InvalidArgument, "amount must be positive")
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"net/url"
+	"strconv"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPConfig is where the smtp mailer sends emails from
+type SMTPConfig struct {
+	// Address is the host:port of the smtp server
+	Address  string
+	Username string
+	Password string
+	// From is the sender address of every email
+	From string
+}
+
+// NewMailer creates the mailer for backend, which is either "smtp" or "memory", the latter only keeps the
+// latest emails and never delivers them, so it has to be picked explicitly
+func NewMailer(backend string, config SMTPConfig) (Mailer, error) {
+	switch backend {
+	case "memory":
+		return NewMemoryMailer(DefaultMemoryMailerCapacity), nil
+	case "smtp":
+		return NewSMTPMailer(config), nil
+	case "":
+		return nil, errors.New("mailer backend is not set")
+	}
+
+	return nil, fmt.Errorf("unsupported mailer backend %s", backend)
+}
+
+// VerifyEmailMessage is the email asking user to open the verification link, verifyURL is the
+// address of the GET /users/verify_email endpoint
+func VerifyEmailMessage(verifyURL string, user db.User, verifyEmail db.VerifyEmail) Message {
+	query := url.Values{}
+	query.Set("email_id", strconv.FormatInt(verifyEmail.ID, 10))
+	query.Set("secret_code", verifyEmail.SecretCode)
+
+	return Message{
+		To:      []string{verifyEmail.Email},
+		Subject: "Welcome to Simple Bank",
+		Body: fmt.Sprintf("Hello %s,\n\nThank you for registering with us!\n"+
+			"Please verify your email address by opening this link before %s:\n%s?%s\n",
+			user.FullName, verifyEmail.ExpiredAt.UTC().Format("2006-01-02 15:04 MST"), verifyURL, query.Encode()),
+	}
+}
+
+// PasswordResetMessage is the email with the code that resets the password of user, resetURL is the
+// address of the page that sends the code to POST /users/password_reset/confirm
+func PasswordResetMessage(resetURL string, user db.User, reset db.PasswordReset) Message {
+	query := url.Values{}
+	query.Set("reset_id", strconv.FormatInt(reset.ID, 10))
+	query.Set("secret_code", reset.SecretCode)
+
+	return Message{
+		To:      []string{user.Email},
+		Subject: "Reset your Simple Bank password",
+		Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password of your account %s.\n"+
+			"Open this link before %s to choose a new one:\n%s?%s\n\n"+
+			"If it wasn't you, ignore this email, your password stays the same.\n",
+			user.FullName, user.Username, reset.ExpiredAt.UTC().Format("2006-01-02 15:04 MST"), resetURL, query.Encode()),
+	}
+}
@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/util/faker"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewMailer(t *testing.T) {
+	mailer, err := NewMailer("memory", SMTPConfig{})
+	require.NoError(t, err)
+	require.IsType(t, &MemoryMailer{}, mailer)
+
+	mailer, err = NewMailer("smtp", SMTPConfig{Address: "localhost:25"})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPMailer{}, mailer)
+
+	_, err = NewMailer("sendgrid", SMTPConfig{})
+	require.Error(t, err)
+
+	// emails would silently never go out
+	_, err = NewMailer("", SMTPConfig{})
+	require.Error(t, err)
+}
+
+func TestMemoryMailer(t *testing.T) {
+	user := faker.NewUser().Get()
+	verifyEmail := db.VerifyEmail{
+		ID:         7,
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: "s3cr3t+code",
+		ExpiredAt:  time.Now().Add(15 * time.Minute),
+	}
+
+	mailer := NewMemoryMailer(10)
+	msg := VerifyEmailMessage("http://localhost:8080/users/verify_email", user, verifyEmail)
+	require.NoError(t, mailer.Send(context.Background(), msg))
+
+	sent := mailer.Sent()
+	require.Len(t, sent, 1)
+	require.Equal(t, []string{user.Email}, sent[0].To)
+	require.Contains(t, sent[0].Body, user.FullName)
+	require.Contains(t, sent[0].Body, "http://localhost:8080/users/verify_email?email_id=7&secret_code=s3cr3t%2Bcode")
+}
+
+func TestPasswordResetMessage(t *testing.T) {
+	user := faker.NewUser().Get()
+	reset := db.PasswordReset{
+		ID:         3,
+		Username:   user.Username,
+		SecretCode: "c0de",
+		ExpiredAt:  time.Now().Add(15 * time.Minute),
+	}
+
+	msg := PasswordResetMessage("http://localhost:8080/users/password_reset/confirm", user, reset)
+	require.Equal(t, []string{user.Email}, msg.To)
+	require.Contains(t, msg.Body, user.Username)
+	require.Contains(t, msg.Body, "http://localhost:8080/users/password_reset/confirm?reset_id=3&secret_code=c0de")
+}
+
+func TestMemoryMailerCapacity(t *testing.T) {
+	mailer := NewMemoryMailer(2)
+	for _, subject := range []string{"1", "2", "3"} {
+		require.NoError(t, mailer.Send(context.Background(), Message{Subject: subject}))
+	}
+
+	sent := mailer.Sent()
+	require.Len(t, sent, 2)
+	require.Equal(t, "2", sent[0].Subject)
+	require.Equal(t, "3", sent[1].Subject)
+}
+
+func TestBuildMessage(t *testing.T) {
+	msg := string(buildMessage("bank@example.com", Message{
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Hello",
+		Body:    "line 1\nline 2",
+	}))
+
+	header, body, ok := strings.Cut(msg, "\r\n\r\n")
+	require.True(t, ok)
+	require.Contains(t, header, "From: bank@example.com\r\n")
+	require.Contains(t, header, "To: a@example.com, b@example.com\r\n")
+	require.Contains(t, header, "Subject: Hello\r\n")
+	require.Equal(t, "line 1\r\nline 2", body)
+}
@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultMemoryMailerCapacity is how many emails the mailer created by NewMailer keeps
+const DefaultMemoryMailerCapacity = 1000
+
+// MemoryMailer keeps the latest emails in the process memory instead of sending them, it fits tests and local development
+type MemoryMailer struct {
+	mu       sync.RWMutex
+	capacity int
+	sent     []Message
+}
+
+func (m *MemoryMailer) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, msg)
+	if len(m.sent) > m.capacity {
+		m.sent = m.sent[len(m.sent)-m.capacity:]
+	}
+	return nil
+}
+
+// Sent returns the kept emails, the oldest first
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]Message(nil), m.sent...)
+}
+
+// NewMemoryMailer creates a mailer that keeps the last capacity emails
+func NewMemoryMailer(capacity int) *MemoryMailer {
+	return &MemoryMailer{capacity: capacity}
+}
@@ -0,0 +1,98 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveSMTP answers a single smtp session on listener and returns the DATA it received
+func serveSMTP(listener net.Listener) <-chan string {
+	received := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO":
+				_ = tp.PrintfLine("250 localhost")
+			case "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				received <- strings.Join(data, "\n")
+				_ = tp.PrintfLine("250 queued")
+			case "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				return
+			default:
+				_ = tp.PrintfLine("250 OK")
+			}
+		}
+	}()
+
+	return received
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := serveSMTP(listener)
+	mailer := NewSMTPMailer(SMTPConfig{Address: listener.Addr().String(), From: "bank@example.com"})
+
+	err = mailer.Send(context.Background(), Message{To: []string{"a@example.com"}, Subject: "Hello", Body: "hi"})
+	require.NoError(t, err)
+
+	select {
+	case data := <-received:
+		require.Contains(t, data, "Subject: Hello")
+		require.Contains(t, data, "hi")
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestSMTPMailerSendHonoursContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// accepts the connection but never greets
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			_, _ = bufio.NewReader(conn).ReadString('\n')
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	mailer := NewSMTPMailer(SMTPConfig{Address: listener.Addr().String(), From: "bank@example.com"})
+	err = mailer.Send(ctx, Message{To: []string{"a@example.com"}, Subject: "Hello", Body: "hi"})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
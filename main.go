@@ -1,32 +1,181 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	_ "github.com/lib/pq"
 	"github.com/vadym-98/simple_bank/api"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/event"
+	"github.com/vadym-98/simple_bank/gapi"
+	"github.com/vadym-98/simple_bank/mail"
+	"github.com/vadym-98/simple_bank/token"
 	"github.com/vadym-98/simple_bank/util"
+	"github.com/vadym-98/simple_bank/webhook"
+	"golang.org/x/sync/errgroup"
 	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 )
 
+// server is the lifecycle shared by the HTTP and gRPC servers
+type server interface {
+	Start(address string) error
+	Shutdown(ctx context.Context) error
+}
+
 func main() {
 	config, err := util.LoadConfig(".")
 	if err != nil {
 		log.Fatal("cannot load config:", err)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		if !runReconcile(ctx, config, os.Args[2:]) {
+			stop()
+			os.Exit(1)
+		}
+		return
+	}
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
 		log.Fatal("can't connect to db: ", err)
 	}
 
-	store := db.NewStore(conn)
-	server, err := api.NewServer(config, store)
+	store := db.NewStoreWithRetryPolicy(conn, db.RetryPolicy{
+		MaxAttempts:    config.TxMaxAttempts,
+		InitialBackoff: config.TxRetryInitialBackoff,
+		MaxBackoff:     config.TxRetryMaxBackoff,
+	})
+
+	// both servers share revocations, so a token revoked over HTTP is rejected by gRPC as well
+	revocations, err := newRevocationStore(config.TokenRevocationBackend, store)
+	if err != nil {
+		log.Fatal("cannot create revocation store:", err)
+	}
+
+	mailer, err := mail.NewMailer(config.MailerBackend, mail.SMTPConfig{
+		Address:  config.SMTPAddress,
+		Username: config.SMTPUsername,
+		Password: config.SMTPPassword,
+		From:     config.EmailSenderAddress,
+	})
+	if err != nil {
+		log.Fatal("cannot create mailer:", err)
+	}
+
+	httpServer, err := api.NewServer(config, store, revocations, mailer)
 	if err != nil {
 		log.Fatal("cannot create server:", err)
 	}
 
-	if err := server.Start(config.ServerAddress); err != nil {
-		log.Fatal("can't start the server:", err)
+	grpcServer, err := gapi.NewServer(config, store, revocations, mailer)
+	if err != nil {
+		log.Fatal("cannot create gRPC server:", err)
+	}
+
+	publisher, err := event.NewPublisher(config.EventPublisher, config.EventWebhookURL)
+	if err != nil {
+		log.Fatal("cannot create event publisher:", err)
+	}
+
+	// workers outlive the servers, so requests still draining can rely on them
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	if config.RevokedTokenPruneInterval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			token.PruneRevokedTokens(workersCtx, revocations, config.RevokedTokenPruneInterval)
+		}()
+	}
+
+	if config.ScheduledTransferInterval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			db.RunScheduledTransfers(workersCtx, store, config.ScheduledTransferInterval)
+		}()
+	}
+
+	if config.EventDispatchInterval > 0 {
+		policy := event.RetryPolicy{
+			MaxAttempts:    config.EventMaxAttempts,
+			InitialBackoff: config.EventRetryInitialBackoff,
+			MaxBackoff:     config.EventRetryMaxBackoff,
+		}
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			event.DispatchEvents(workersCtx, store, publisher, config.EventDispatchInterval, policy)
+		}()
+	}
+
+	if config.WebhookDeliveryInterval > 0 {
+		deliverer := webhook.NewDeliverer(webhook.RetryPolicy{
+			MaxAttempts:    config.WebhookMaxAttempts,
+			InitialBackoff: config.WebhookRetryInitialBackoff,
+			MaxBackoff:     config.WebhookRetryMaxBackoff,
+		})
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			webhook.RunDeliveries(workersCtx, store, deliverer, config.WebhookDeliveryInterval)
+		}()
+	}
+
+	servers, ctx := errgroup.WithContext(ctx)
+	runServer(ctx, servers, "HTTP", httpServer, config.ServerAddress, config)
+	runServer(ctx, servers, "gRPC", grpcServer, config.GRPCServerAddress, config)
+
+	serversErr := servers.Wait()
+
+	stopWorkers()
+	workers.Wait()
+	log.Println("background workers stopped")
+
+	// the pool is closed last, once neither requests nor workers use it
+	if err := conn.Close(); err != nil {
+		log.Println("cannot close db connection:", err)
+	}
+
+	if serversErr != nil {
+		log.Fatal("server failed:", serversErr)
 	}
 }
+
+// runServer starts srv and shuts it down once ctx is done, giving running requests config.ShutdownTimeout to finish
+func runServer(ctx context.Context, group *errgroup.Group, name string, srv server, address string, config util.Config) {
+	group.Go(func() error {
+		log.Printf("start %s server at %s", name, address)
+		return srv.Start(address)
+	})
+
+	group.Go(func() error {
+		<-ctx.Done()
+		log.Printf("shutting down %s server", name)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			log.Printf("%s server didn't drain in %s, cancelled running requests", name, config.ShutdownTimeout)
+		}
+
+		log.Printf("%s server stopped", name)
+		return nil
+	})
+}
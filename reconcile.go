@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/util"
+	"log"
+	"os"
+)
+
+// runReconcile implements `simple_bank reconcile`, it prints the report as JSON
+// and tells whether every discrepancy was corrected
+func runReconcile(ctx context.Context, config util.Config, args []string) bool {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	batchSize := flags.Int("batch-size", db.DefaultReconcileBatchSize, "accounts and transfers read per query")
+	fix := flags.Bool("fix", false, "write adjustment entries for customer accounts whose entries don't add up to the balance (admin only)")
+	_ = flags.Parse(args)
+
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		log.Fatal("can't connect to db: ", err)
+	}
+	defer conn.Close()
+
+	store := db.NewStoreWithRetryPolicy(conn, db.RetryPolicy{
+		MaxAttempts:    config.TxMaxAttempts,
+		InitialBackoff: config.TxRetryInitialBackoff,
+		MaxBackoff:     config.TxRetryMaxBackoff,
+	})
+
+	report, err := store.Reconcile(ctx, db.ReconcileParams{
+		BatchSize: int32(*batchSize),
+		Fix:       *fix,
+	})
+	if err != nil {
+		log.Fatal("cannot reconcile:", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal("cannot write report:", err)
+	}
+
+	if len(report.Transfers) > 0 {
+		return false
+	}
+	for _, discrepancy := range report.Balances {
+		if discrepancy.JournalEntryID == 0 {
+			return false
+		}
+	}
+	return true
+}
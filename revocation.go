@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/token"
+)
+
+// newRevocationStore creates the store for backend, which is either "postgres" or "memory"
+func newRevocationStore(backend string, queries db.Querier) (token.RevocationStore, error) {
+	switch backend {
+	case "", "postgres":
+		return &sqlRevocationStore{queries: queries}, nil
+	case "memory":
+		return token.NewMemoryRevocationStore(queries), nil
+	}
+
+	return nil, fmt.Errorf("unsupported token revocation backend %s", backend)
+}
+
+// sqlRevocationStore keeps revoked tokens in the revoked_tokens table, so all server instances share them
+type sqlRevocationStore struct {
+	queries db.Querier
+}
+
+func (s *sqlRevocationStore) Revoke(ctx context.Context, payload *token.Payload) error {
+	return s.queries.RevokeToken(ctx, db.RevokeTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	})
+}
+
+func (s *sqlRevocationStore) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	return s.queries.IsTokenRevoked(ctx, db.IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})
+}
+
+func (s *sqlRevocationStore) Prune(ctx context.Context) (int64, error) {
+	return s.queries.DeleteExpiredRevokedTokens(ctx)
+}
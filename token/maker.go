@@ -3,7 +3,8 @@ package token
 import "time"
 
 type Maker interface {
-	CreateToken(username string, duration time.Duration) (string, error)
+	CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
 
-	VerifyToken(token string) (*Payload, error)
+	// VerifyToken returns ErrInvalidToken for a token of another type than tokenType
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
 }
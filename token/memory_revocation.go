@@ -0,0 +1,70 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// MemoryRevocationStore keeps revoked tokens in the process memory, so it only fits a single server instance
+// Password changes are still looked up in the users table, so they outlive restarts like with the SQL store
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	revoked map[uuid.UUID]time.Time
+	users   PasswordChanges
+}
+
+func (m *MemoryRevocationStore) Revoke(_ context.Context, payload *Payload) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revoked[payload.ID] = payload.ExpiredAt
+	return nil
+}
+
+func (m *MemoryRevocationStore) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	m.mu.RLock()
+	_, revoked := m.revoked[payload.ID]
+	m.mu.RUnlock()
+
+	if revoked {
+		return true, nil
+	}
+
+	changedAt, err := m.users.GetUserPasswordChangedAt(ctx, payload.Username)
+	if err != nil {
+		// like the SQL store, a token of an unknown user isn't revoked by a password change
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return payload.IssuedAt.Before(changedAt), nil
+}
+
+func (m *MemoryRevocationStore) Prune(_ context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pruned int64
+	now := time.Now()
+	for id, expiredAt := range m.revoked {
+		if now.After(expiredAt) {
+			delete(m.revoked, id)
+			pruned++
+		}
+	}
+
+	return pruned, nil
+}
+
+func NewMemoryRevocationStore(users PasswordChanges) RevocationStore {
+	return &MemoryRevocationStore{
+		revoked: make(map[uuid.UUID]time.Time),
+		users:   users,
+	}
+}
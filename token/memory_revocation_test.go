@@ -0,0 +1,87 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"github.com/vadym-98/simple_bank/util"
+	"testing"
+	"time"
+)
+
+// passwordChanges stands in for the users table, users missing from it never changed the password
+type passwordChanges map[string]time.Time
+
+func (p passwordChanges) GetUserPasswordChangedAt(_ context.Context, username string) (time.Time, error) {
+	changedAt, ok := p[username]
+	if !ok {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return changedAt, nil
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	store := NewMemoryRevocationStore(passwordChanges{})
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = store.Revoke(context.Background(), payload)
+	require.NoError(t, err)
+
+	revoked, err = store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// tokens that haven't expired yet must survive pruning
+	pruned, err := store.Prune(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+
+	expiredPayload, err := NewPayload(util.RandomOwner(), util.DepositorRole, TokenTypeAccessToken, -time.Minute)
+	require.NoError(t, err)
+
+	err = store.Revoke(context.Background(), expiredPayload)
+	require.NoError(t, err)
+
+	pruned, err = store.Prune(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pruned)
+
+	revoked, err = store.IsRevoked(context.Background(), expiredPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestMemoryRevocationStorePasswordChange(t *testing.T) {
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	changedAt := payload.IssuedAt.Add(time.Second)
+	store := NewMemoryRevocationStore(passwordChanges{payload.Username: changedAt})
+
+	revoked, err := store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// tokens issued after the change and tokens of other users are fine
+	later := *payload
+	later.IssuedAt = changedAt.Add(time.Second)
+	revoked, err = store.IsRevoked(context.Background(), &later)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	other, err := NewPayload(util.RandomOwner(), util.DepositorRole, TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+	revoked, err = store.IsRevoked(context.Background(), other)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -40,31 +40,32 @@ func (m *MockMaker) EXPECT() *MockMakerMockRecorder {
 }
 
 // CreateToken mocks base method.
-func (m *MockMaker) CreateToken(arg0 string, arg1 time.Duration) (string, error) {
+func (m *MockMaker) CreateToken(arg0, arg1 string, arg2 token.TokenType, arg3 time.Duration) (string, *token.Payload, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateToken", arg0, arg1)
+	ret := m.ctrl.Call(m, "CreateToken", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(*token.Payload)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // CreateToken indicates an expected call of CreateToken.
-func (mr *MockMakerMockRecorder) CreateToken(arg0, arg1 any) *gomock.Call {
+func (mr *MockMakerMockRecorder) CreateToken(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockMaker)(nil).CreateToken), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockMaker)(nil).CreateToken), arg0, arg1, arg2, arg3)
 }
 
 // VerifyToken mocks base method.
-func (m *MockMaker) VerifyToken(arg0 string) (*token.Payload, error) {
+func (m *MockMaker) VerifyToken(arg0 string, arg1 token.TokenType) (*token.Payload, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "VerifyToken", arg0)
+	ret := m.ctrl.Call(m, "VerifyToken", arg0, arg1)
 	ret0, _ := ret[0].(*token.Payload)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // VerifyToken indicates an expected call of VerifyToken.
-func (mr *MockMakerMockRecorder) VerifyToken(arg0 any) *gomock.Call {
+func (mr *MockMakerMockRecorder) VerifyToken(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyToken", reflect.TypeOf((*MockMaker)(nil).VerifyToken), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyToken", reflect.TypeOf((*MockMaker)(nil).VerifyToken), arg0, arg1)
 }
@@ -0,0 +1,39 @@
+package token
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RevocationStore keeps track of tokens that were invalidated before they expired
+type RevocationStore interface {
+	Revoke(ctx context.Context, payload *Payload) error
+	// IsRevoked reports whether the token was revoked by itself or by a password change after it was issued,
+	// the latter is checked against password_changed_at of the user whatever the backend
+	IsRevoked(ctx context.Context, payload *Payload) (bool, error)
+	// Prune forgets revoked tokens that have expired anyway and returns how many were removed
+	Prune(ctx context.Context) (int64, error)
+}
+
+// PasswordChanges looks up when a user last changed the password, the user store implements it
+type PasswordChanges interface {
+	GetUserPasswordChangedAt(ctx context.Context, username string) (time.Time, error)
+}
+
+// PruneRevokedTokens prunes the store every interval until ctx is cancelled
+func PruneRevokedTokens(ctx context.Context, store RevocationStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := store.Prune(ctx); err != nil {
+				log.Println("failed to prune revoked tokens:", err)
+			}
+		}
+	}
+}
@@ -5,12 +5,67 @@ import (
 	"time"
 )
 
+// DevelopmentEnvironment is the Environment of local setups, webhooks can use plain http in it
+const DevelopmentEnvironment = "development"
+
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	// Environment is either "development" or "production"
+	Environment          string        `mapstructure:"ENVIRONMENT"`
+	DBDriver             string        `mapstructure:"DB_DRIVER"`
+	DBSource             string        `mapstructure:"DB_SOURCE"`
+	ServerAddress        string        `mapstructure:"SERVER_ADDRESS"`
+	GRPCServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	// TokenRevocationBackend is either "postgres" or "memory", the latter only fits a single server instance
+	TokenRevocationBackend    string        `mapstructure:"TOKEN_REVOCATION_BACKEND"`
+	RevokedTokenPruneInterval time.Duration `mapstructure:"REVOKED_TOKEN_PRUNE_INTERVAL"`
+	// FXRatesFile is a JSON file with the rate of every currency against a common base
+	FXRatesFile string `mapstructure:"FX_RATES_FILE"`
+	// CurrencyCacheTTL is how long enabled currencies are cached before being read again
+	CurrencyCacheTTL time.Duration `mapstructure:"CURRENCY_CACHE_TTL"`
+	// ShutdownTimeout is how long the servers wait for running requests to finish when stopping
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	// RequestTimeout bounds the db statements of a request, the ones still running once it passes
+	// are cancelled and their transaction is rolled back
+	RequestTimeout time.Duration `mapstructure:"REQUEST_TIMEOUT"`
+	// TxMaxAttempts is how often a transaction aborted by a serialization failure or a deadlock is run, retries back off
+	// from TxRetryInitialBackoff up to TxRetryMaxBackoff
+	TxMaxAttempts         int           `mapstructure:"TX_MAX_ATTEMPTS"`
+	TxRetryInitialBackoff time.Duration `mapstructure:"TX_RETRY_INITIAL_BACKOFF"`
+	TxRetryMaxBackoff     time.Duration `mapstructure:"TX_RETRY_MAX_BACKOFF"`
+	// ScheduledTransferInterval is how often due scheduled transfers are looked for, 0 disables the worker
+	ScheduledTransferInterval time.Duration `mapstructure:"SCHEDULED_TRANSFER_INTERVAL"`
+	// EventPublisher is either "memory" or "webhook", the latter posts every outbox event to EventWebhookURL
+	EventPublisher  string `mapstructure:"EVENT_PUBLISHER"`
+	EventWebhookURL string `mapstructure:"EVENT_WEBHOOK_URL"`
+	// EventDispatchInterval is how often unsent outbox events are published, 0 disables the dispatcher
+	// A failed event is tried again backing off from EventRetryInitialBackoff up to EventRetryMaxBackoff,
+	// once it failed EventMaxAttempts times it is dead and no longer published
+	EventDispatchInterval    time.Duration `mapstructure:"EVENT_DISPATCH_INTERVAL"`
+	EventMaxAttempts         int32         `mapstructure:"EVENT_MAX_ATTEMPTS"`
+	EventRetryInitialBackoff time.Duration `mapstructure:"EVENT_RETRY_INITIAL_BACKOFF"`
+	EventRetryMaxBackoff     time.Duration `mapstructure:"EVENT_RETRY_MAX_BACKOFF"`
+	// WebhookDeliveryInterval is how often due webhook deliveries are attempted, 0 disables the worker
+	// A failed delivery is attempted up to WebhookMaxAttempts times, backing off from WebhookRetryInitialBackoff
+	// up to WebhookRetryMaxBackoff
+	WebhookDeliveryInterval    time.Duration `mapstructure:"WEBHOOK_DELIVERY_INTERVAL"`
+	WebhookMaxAttempts         int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryInitialBackoff time.Duration `mapstructure:"WEBHOOK_RETRY_INITIAL_BACKOFF"`
+	WebhookRetryMaxBackoff     time.Duration `mapstructure:"WEBHOOK_RETRY_MAX_BACKOFF"`
+	// MailerBackend is either "smtp" or "memory", the latter keeps emails in memory instead of sending them
+	MailerBackend      string `mapstructure:"MAILER_BACKEND"`
+	SMTPAddress        string `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername       string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword       string `mapstructure:"SMTP_PASSWORD"`
+	EmailSenderAddress string `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	// VerifyEmailURL is the public address of GET /users/verify_email, verification emails link to it
+	VerifyEmailURL string `mapstructure:"VERIFY_EMAIL_URL"`
+	// RequireVerifiedEmail rejects transfers of users who haven't verified their email yet
+	RequireVerifiedEmail bool `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	// PasswordResetURL is the public address of the page that confirms a password reset, reset emails link to it
+	PasswordResetURL string `mapstructure:"PASSWORD_RESET_URL"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
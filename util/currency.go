@@ -1,15 +1,95 @@
 package util
 
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
 	CAD = "CAD"
 )
 
-func IsSupportedCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, CAD:
-		return true
+// Currency describes an ISO 4217 currency known to the bank
+type Currency struct {
+	Code string
+	// Exponent is the number of minor unit digits, 2 for cents
+	Exponent int16
+	Enabled  bool
+}
+
+// CurrencyLoader reads all known currencies from their source of truth
+type CurrencyLoader func(ctx context.Context) ([]Currency, error)
+
+// CurrencyRegistry caches currencies so they can be checked on every request
+// The cache is reloaded once it is older than ttl, so enabling a currency needs no redeploy
+type CurrencyRegistry struct {
+	load CurrencyLoader
+	ttl  time.Duration
+
+	mu         sync.RWMutex
+	currencies map[string]Currency
+	loadedAt   time.Time
+}
+
+func NewCurrencyRegistry(load CurrencyLoader, ttl time.Duration) *CurrencyRegistry {
+	return &CurrencyRegistry{
+		load: load,
+		ttl:  ttl,
+	}
+}
+
+// Get returns a known currency by its code, including disabled ones that existing accounts may still hold
+func (r *CurrencyRegistry) Get(ctx context.Context, code string) (Currency, bool) {
+	currencies := r.cached()
+	if currencies == nil || r.expired() {
+		currencies = r.reload(ctx)
+	}
+
+	currency, ok := currencies[code]
+	return currency, ok
+}
+
+// IsSupported reports whether new accounts and transfers may use the currency
+func (r *CurrencyRegistry) IsSupported(ctx context.Context, code string) bool {
+	currency, ok := r.Get(ctx, code)
+	return ok && currency.Enabled
+}
+
+func (r *CurrencyRegistry) cached() map[string]Currency {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.currencies
+}
+
+func (r *CurrencyRegistry) expired() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.ttl > 0 && time.Since(r.loadedAt) > r.ttl
+}
+
+// reload keeps serving the stale currencies if the loader fails
+func (r *CurrencyRegistry) reload(ctx context.Context) map[string]Currency {
+	list, err := r.load(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		log.Println("failed to load currencies:", err)
+		return r.currencies
 	}
-	return false
+
+	r.currencies = make(map[string]Currency, len(list))
+	for _, currency := range list {
+		r.currencies[currency.Code] = currency
+	}
+	r.loadedAt = time.Now()
+
+	return r.currencies
 }
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCurrencyRegistry(t *testing.T) {
+	loads := 0
+	registry := NewCurrencyRegistry(func(ctx context.Context) ([]Currency, error) {
+		loads++
+		return []Currency{
+			{Code: USD, Exponent: 2, Enabled: true},
+			{Code: "GBP", Exponent: 2, Enabled: false},
+		}, nil
+	}, time.Hour)
+
+	currency, ok := registry.Get(context.Background(), USD)
+	require.True(t, ok)
+	require.Equal(t, int16(2), currency.Exponent)
+
+	require.False(t, registry.IsSupported(context.Background(), "GBP"))
+	_, ok = registry.Get(context.Background(), "GBP")
+	require.True(t, ok)
+	require.False(t, registry.IsSupported(context.Background(), EUR))
+
+	// the cache is fresh, so the loader runs only once
+	require.Equal(t, 1, loads)
+}
+
+func TestCurrencyRegistryReload(t *testing.T) {
+	enabled := false
+	var loadErr error
+	registry := NewCurrencyRegistry(func(ctx context.Context) ([]Currency, error) {
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return []Currency{{Code: CAD, Exponent: 2, Enabled: enabled}}, nil
+	}, time.Nanosecond)
+
+	require.False(t, registry.IsSupported(context.Background(), CAD))
+
+	enabled = true
+	time.Sleep(time.Millisecond)
+	require.True(t, registry.IsSupported(context.Background(), CAD))
+
+	// a failed reload keeps the last known currencies
+	loadErr = errors.New("connection refused")
+	time.Sleep(time.Millisecond)
+	require.True(t, registry.IsSupported(context.Background(), CAD))
+}
@@ -24,6 +24,16 @@ func (ab *AccountBuilder) WithCurrency(c string) *AccountBuilder {
 	return ab
 }
 
+func (ab *AccountBuilder) WithStatus(s db.AccountStatus) *AccountBuilder {
+	ab.account.Status = s
+	return ab
+}
+
+func (ab *AccountBuilder) WithKind(k db.AccountKind) *AccountBuilder {
+	ab.account.Kind = k
+	return ab
+}
+
 func (ab *AccountBuilder) Get() db.Account {
 	return ab.account
 }
@@ -35,6 +45,8 @@ func NewAccount() *AccountBuilder {
 			Owner:    util.RandomOwner(),
 			Balance:  util.RandomMoney(),
 			Currency: util.RandomCurrency(),
+			Status:   db.AccountStatusActive,
+			Kind:     db.AccountKindCustomer,
 		},
 	}
 }
@@ -1,6 +1,7 @@
 package faker
 
 import (
+	"database/sql"
 	db "github.com/vadym-98/simple_bank/db/sqlc"
 	"github.com/vadym-98/simple_bank/util"
 )
@@ -19,6 +20,16 @@ func (eb *EntryBuilder) WithAmount(amount int64) *EntryBuilder {
 	return eb
 }
 
+func (eb *EntryBuilder) WithType(entryType db.EntryType) *EntryBuilder {
+	eb.entry.EntryType = entryType
+	return eb
+}
+
+func (eb *EntryBuilder) WithTransferID(id int64) *EntryBuilder {
+	eb.entry.TransferID = sql.NullInt64{Int64: id, Valid: true}
+	return eb
+}
+
 func (eb *EntryBuilder) Get() db.Entry {
 	return eb.entry
 }
@@ -29,6 +40,7 @@ func NewEntry() *EntryBuilder {
 			ID:        util.RandomInt(1, 1000),
 			AccountID: util.RandomInt(1, 1000),
 			Amount:    util.RandomMoney(),
+			EntryType: db.EntryTypeDeposit,
 		},
 	}
 }
@@ -0,0 +1,53 @@
+package faker
+
+import (
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/util"
+	"time"
+)
+
+type ScheduledTransferBuilder struct {
+	scheduled db.ScheduledTransfer
+}
+
+func (sb *ScheduledTransferBuilder) WithOwner(owner string) *ScheduledTransferBuilder {
+	sb.scheduled.Owner = owner
+	return sb
+}
+
+func (sb *ScheduledTransferBuilder) WithFromAccountID(id int64) *ScheduledTransferBuilder {
+	sb.scheduled.FromAccountID = id
+	return sb
+}
+
+func (sb *ScheduledTransferBuilder) WithToAccountID(id int64) *ScheduledTransferBuilder {
+	sb.scheduled.ToAccountID = id
+	return sb
+}
+
+func (sb *ScheduledTransferBuilder) WithStatus(s db.ScheduledTransferStatus) *ScheduledTransferBuilder {
+	sb.scheduled.Status = s
+	return sb
+}
+
+func (sb *ScheduledTransferBuilder) Get() db.ScheduledTransfer {
+	return sb.scheduled
+}
+
+func NewScheduledTransfer() *ScheduledTransferBuilder {
+	startAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	return &ScheduledTransferBuilder{
+		scheduled: db.ScheduledTransfer{
+			ID:            util.RandomInt(1, 1000),
+			Owner:         util.RandomOwner(),
+			FromAccountID: util.RandomInt(1, 1000),
+			ToAccountID:   util.RandomInt(1, 1000),
+			Amount:        util.RandomMoney(),
+			Recurrence:    db.RecurrenceMonthly,
+			StartAt:       startAt,
+			NextRunAt:     startAt,
+			Status:        db.ScheduledTransferStatusActive,
+		},
+	}
+}
@@ -24,12 +24,16 @@ func (tb *TransferBuilder) Get() db.Transfer {
 }
 
 func NewTransfer() *TransferBuilder {
+	amount := util.RandomMoney()
+
 	return &TransferBuilder{
 		transfer: db.Transfer{
 			ID:            util.RandomInt(1, 1000),
 			FromAccountID: util.RandomInt(1, 1000),
 			ToAccountID:   util.RandomInt(1, 1000),
-			Amount:        util.RandomMoney(),
+			Amount:        amount,
+			ToAmount:      amount,
+			ExchangeRate:  1,
 		},
 	}
 }
@@ -9,6 +9,11 @@ type UserBuilder struct {
 	user db.User
 }
 
+func (ub *UserBuilder) WithRole(role string) *UserBuilder {
+	ub.user.Role = role
+	return ub
+}
+
 func (ub *UserBuilder) Get() db.User {
 	return ub.user
 }
@@ -19,6 +24,7 @@ func NewUser() *UserBuilder {
 			Username: util.RandomOwner(),
 			FullName: util.RandomOwner(),
 			Email:    util.RandomEmail(),
+			Role:     util.DepositorRole,
 		},
 	}
 }
@@ -0,0 +1,39 @@
+package faker
+
+import (
+	"fmt"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"github.com/vadym-98/simple_bank/util"
+	"time"
+)
+
+type WebhookBuilder struct {
+	webhook db.Webhook
+}
+
+func (wb *WebhookBuilder) WithOwner(owner string) *WebhookBuilder {
+	wb.webhook.Owner = owner
+	return wb
+}
+
+func (wb *WebhookBuilder) WithAccountID(id int64) *WebhookBuilder {
+	wb.webhook.AccountID = id
+	return wb
+}
+
+func (wb *WebhookBuilder) Get() db.Webhook {
+	return wb.webhook
+}
+
+func NewWebhook() *WebhookBuilder {
+	return &WebhookBuilder{
+		webhook: db.Webhook{
+			ID:        util.RandomInt(1, 1000),
+			Owner:     util.RandomOwner(),
+			AccountID: util.RandomInt(1, 1000),
+			Url:       fmt.Sprintf("https://%s.example.com/hooks", util.RandomString(8)),
+			Secret:    util.RandomString(64),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+		},
+	}
+}
@@ -0,0 +1,161 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+)
+
+var (
+	// ErrUnsupportedCurrencyPair is returned when no exchange rate is known for a pair of currencies
+	ErrUnsupportedCurrencyPair = errors.New("unsupported currency pair")
+	// ErrConversionTooSmall is returned when an amount converts to less than half a minor unit of the other currency
+	ErrConversionTooSmall = errors.New("amount is too small to be converted")
+)
+
+// FXRateProvider returns exchange rates between supported currencies
+type FXRateProvider interface {
+	// Rate returns how many units of the to currency one unit of the from currency buys
+	Rate(ctx context.Context, from string, to string) (float64, error)
+}
+
+// FXQuote is the result of converting an amount at the current rate
+type FXQuote struct {
+	Rate            float64 `json:"rate"`
+	Amount          Money   `json:"amount"`
+	ConvertedAmount Money   `json:"converted_amount"`
+}
+
+// QuoteFX converts amount to another currency, rounding to the nearest minor unit of that currency
+// The conversion uses exact fractions of the rate's decimal form, so no precision is lost to floating point
+func QuoteFX(ctx context.Context, provider FXRateProvider, amount Money, to Currency) (FXQuote, error) {
+	rate, err := provider.Rate(ctx, amount.Currency, to.Code)
+	if err != nil {
+		return FXQuote{}, err
+	}
+
+	exactRate, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'g', -1, 64))
+	if !ok || exactRate.Sign() <= 0 {
+		return FXQuote{}, fmt.Errorf("invalid fx rate %v for %s/%s", rate, amount.Currency, to.Code)
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Amount), exactRate)
+
+	// minor units differ when the currencies have different exponents, like USD cents and JPY
+	exponent := int64(to.Exponent) - int64(amount.Exponent)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(abs(exponent)), nil))
+	if exponent >= 0 {
+		converted.Mul(converted, scale)
+	} else {
+		converted.Quo(converted, scale)
+	}
+
+	rounded := roundRat(converted)
+	if !rounded.IsInt64() {
+		return FXQuote{}, ErrMoneyOverflow
+	}
+	if rounded.Sign() == 0 && amount.Amount != 0 {
+		return FXQuote{}, fmt.Errorf("%w: %s is worth less than a minor unit of %s", ErrConversionTooSmall, amount, to.Code)
+	}
+
+	quote := FXQuote{
+		Rate:            rate,
+		Amount:          amount,
+		ConvertedAmount: NewMoney(rounded.Int64(), to),
+	}
+
+	return quote, nil
+}
+
+// roundRat rounds x to the nearest integer, halves away from zero
+func roundRat(x *big.Rat) *big.Int {
+	quo, rem := new(big.Int).QuoRem(new(big.Int).Abs(x.Num()), x.Denom(), new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(x.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+
+	if x.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return quo
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// StaticFXRateProvider serves fixed rates kept in memory
+// Rates are stored against a common base currency, so any two known currencies can be converted
+type StaticFXRateProvider struct {
+	rates map[string]float64
+}
+
+// NewStaticFXRateProvider creates a provider from how many units of each currency one unit of the base buys
+func NewStaticFXRateProvider(rates map[string]float64) *StaticFXRateProvider {
+	p := &StaticFXRateProvider{rates: make(map[string]float64, len(rates))}
+	for currency, rate := range rates {
+		p.rates[currency] = rate
+	}
+
+	return p
+}
+
+// NewFXRateProvider loads rates from path, without it only same-currency transfers are possible
+func NewFXRateProvider(path string) (FXRateProvider, error) {
+	if path == "" {
+		return NewStaticFXRateProvider(nil), nil
+	}
+
+	provider, err := LoadStaticFXRateProvider(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load fx rates: %w", err)
+	}
+
+	return provider, nil
+}
+
+// LoadStaticFXRateProvider reads rates from a JSON file like {"USD": 1, "EUR": 0.92}
+func LoadStaticFXRateProvider(path string) (*StaticFXRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates map[string]float64
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("cannot parse fx rates file %s: %w", path, err)
+	}
+
+	for currency, rate := range rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("fx rate of %s must be positive", currency)
+		}
+	}
+
+	return NewStaticFXRateProvider(rates), nil
+}
+
+func (p *StaticFXRateProvider) Rate(_ context.Context, from string, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s/%s", ErrUnsupportedCurrencyPair, from, to)
+	}
+
+	toRate, ok := p.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s/%s", ErrUnsupportedCurrencyPair, from, to)
+	}
+
+	return toRate / fromRate, nil
+}
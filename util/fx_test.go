@@ -0,0 +1,89 @@
+package util
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticFXRateProvider(t *testing.T) {
+	provider := NewStaticFXRateProvider(map[string]float64{USD: 1, EUR: 0.5})
+
+	rate, err := provider.Rate(context.Background(), USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, 0.5, rate)
+
+	rate, err = provider.Rate(context.Background(), EUR, USD)
+	require.NoError(t, err)
+	require.Equal(t, 2.0, rate)
+
+	rate, err = provider.Rate(context.Background(), CAD, CAD)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, rate)
+
+	_, err = provider.Rate(context.Background(), USD, CAD)
+	require.ErrorIs(t, err, ErrUnsupportedCurrencyPair)
+}
+
+func TestQuoteFX(t *testing.T) {
+	eur := Currency{Code: EUR, Exponent: 2, Enabled: true}
+	provider := NewStaticFXRateProvider(map[string]float64{USD: 1, EUR: 0.925, "JPY": 150})
+
+	quote, err := QuoteFX(context.Background(), provider, NewMoney(101, usd), eur)
+	require.NoError(t, err)
+	require.Equal(t, 0.925, quote.Rate)
+	require.Equal(t, NewMoney(101, usd), quote.Amount)
+	require.Equal(t, NewMoney(93, eur), quote.ConvertedAmount)
+
+	// 1.01 USD buys 151.5 yen, rounded to whole yen
+	quote, err = QuoteFX(context.Background(), provider, NewMoney(101, usd), jpy)
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(152, jpy), quote.ConvertedAmount)
+
+	quote, err = QuoteFX(context.Background(), provider, NewMoney(150, jpy), usd)
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(100, usd), quote.ConvertedAmount)
+
+	// a yen is worth 0.67 cents, rounded up to a cent, while half a cent isn't worth a yen
+	quote, err = QuoteFX(context.Background(), provider, NewMoney(1, jpy), usd)
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1, usd), quote.ConvertedAmount)
+
+	_, err = QuoteFX(context.Background(), provider, NewMoney(0, usd), jpy)
+	require.NoError(t, err)
+
+	_, err = QuoteFX(context.Background(), NewStaticFXRateProvider(map[string]float64{USD: 1, "JPY": 250}), NewMoney(1, jpy), usd)
+	require.ErrorIs(t, err, ErrConversionTooSmall)
+}
+
+func TestQuoteFXPrecision(t *testing.T) {
+	eur := Currency{Code: EUR, Exponent: 2, Enabled: true}
+	provider := NewStaticFXRateProvider(map[string]float64{USD: 1, EUR: 1, "JPY": 0.5})
+
+	// 2^53 + 1 has no float64 of its own
+	quote, err := QuoteFX(context.Background(), provider, NewMoney(1<<53+1, usd), eur)
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1<<53+1, eur), quote.ConvertedAmount)
+
+	_, err = QuoteFX(context.Background(), provider, NewMoney(math.MaxInt64, jpy), usd)
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+}
+
+func TestLoadStaticFXRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD": 1, "EUR": 0.5}`), 0o600))
+
+	provider, err := LoadStaticFXRateProvider(path)
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, 0.5, rate)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD": 0}`), 0o600))
+	_, err = LoadStaticFXRateProvider(path)
+	require.Error(t, err)
+}
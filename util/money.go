@@ -0,0 +1,156 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMoneyOverflow is returned when an amount doesn't fit into int64 minor units
+	ErrMoneyOverflow = errors.New("money amount overflows")
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies
+	ErrCurrencyMismatch = errors.New("money currencies don't match")
+	// ErrInvalidMoney is returned for decimal strings that don't describe an amount of the currency
+	ErrInvalidMoney = errors.New("invalid money amount")
+)
+
+// Money is an amount in the minor units of its currency, so 1234 with USD is 12.34 dollars
+type Money struct {
+	Amount   int64
+	Currency string
+	// Exponent is the number of minor unit digits of Currency
+	Exponent int16
+}
+
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{
+		Amount:   amount,
+		Currency: currency.Code,
+		Exponent: currency.Exponent,
+	}
+}
+
+// ParseMoney reads a decimal string like "12.34" or "-5" with at most as many fractional digits as the currency has
+func ParseMoney(value string, currency Currency) (Money, error) {
+	digits := value
+	negative := strings.HasPrefix(digits, "-")
+	if negative {
+		digits = digits[1:]
+	}
+
+	whole, fraction, hasFraction := strings.Cut(digits, ".")
+	if whole == "" || (hasFraction && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+
+	if len(fraction) > int(currency.Exponent) {
+		return Money{}, fmt.Errorf("%w: %s allows %d decimal places", ErrInvalidMoney, currency.Code, currency.Exponent)
+	}
+	fraction += strings.Repeat("0", int(currency.Exponent)-len(fraction))
+
+	minor := whole + fraction
+	if negative {
+		minor = "-" + minor
+	}
+
+	amount, err := strconv.ParseInt(minor, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, ErrMoneyOverflow
+		}
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+
+	return NewMoney(amount, currency), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String prints the amount as a decimal with exactly Exponent fractional digits
+func (m Money) String() string {
+	sign := ""
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		abs = uint64(-(m.Amount + 1)) + 1
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	exp := int(m.Exponent)
+	if exp == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// Add sums two amounts of the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency || m.Exponent != other.Exponent {
+		return Money{}, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	m.Amount += other.Amount
+	return m, nil
+}
+
+// Sub subtracts an amount of the same currency
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	other.Amount = -other.Amount
+	return m.Add(other)
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON keeps the amount a string, so clients don't lose precision or guess the units
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.String(), Currency: m.Currency})
+}
+
+// UnmarshalJSON takes the exponent from the number of fractional digits, as written by MarshalJSON
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	exponent := 0
+	if _, fraction, ok := strings.Cut(v.Amount, "."); ok {
+		exponent = len(fraction)
+	}
+
+	money, err := ParseMoney(v.Amount, Currency{Code: v.Currency, Exponent: int16(exponent)})
+	if err != nil {
+		return err
+	}
+
+	*m = money
+	return nil
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+var (
+	usd = Currency{Code: USD, Exponent: 2, Enabled: true}
+	jpy = Currency{Code: "JPY", Exponent: 0, Enabled: true}
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		value    string
+		currency Currency
+		amount   int64
+		err      error
+	}{
+		{value: "12.34", currency: usd, amount: 1234},
+		{value: "12.3", currency: usd, amount: 1230},
+		{value: "12", currency: usd, amount: 1200},
+		{value: "-0.05", currency: usd, amount: -5},
+		{value: "1500", currency: jpy, amount: 1500},
+		{value: "92233720368547758.07", currency: usd, amount: math.MaxInt64},
+		{value: "92233720368547758.08", currency: usd, err: ErrMoneyOverflow},
+		{value: "12.345", currency: usd, err: ErrInvalidMoney},
+		{value: "1.5", currency: jpy, err: ErrInvalidMoney},
+		{value: "", currency: usd, err: ErrInvalidMoney},
+		{value: "12.", currency: usd, err: ErrInvalidMoney},
+		{value: ".5", currency: usd, err: ErrInvalidMoney},
+		{value: "+1", currency: usd, err: ErrInvalidMoney},
+		{value: "1e3", currency: usd, err: ErrInvalidMoney},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			money, err := ParseMoney(tc.value, tc.currency)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, NewMoney(tc.amount, tc.currency), money)
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	require.Equal(t, "12.34", NewMoney(1234, usd).String())
+	require.Equal(t, "0.05", NewMoney(5, usd).String())
+	require.Equal(t, "-0.05", NewMoney(-5, usd).String())
+	require.Equal(t, "0.00", NewMoney(0, usd).String())
+	require.Equal(t, "1500", NewMoney(1500, jpy).String())
+	require.Equal(t, "-92233720368547758.08", NewMoney(math.MinInt64, usd).String())
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	sum, err := NewMoney(1050, usd).Add(NewMoney(-50, usd))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1000, usd), sum)
+
+	diff, err := NewMoney(1000, usd).Sub(NewMoney(1050, usd))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(-50, usd), diff)
+
+	_, err = NewMoney(math.MaxInt64, usd).Add(NewMoney(1, usd))
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+
+	_, err = NewMoney(math.MinInt64, usd).Sub(NewMoney(1, usd))
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+
+	_, err = NewMoney(0, usd).Sub(NewMoney(math.MinInt64, usd))
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+
+	_, err = NewMoney(1, usd).Add(NewMoney(1, jpy))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	money := NewMoney(-1234, usd)
+
+	data, err := json.Marshal(money)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amount": "-12.34", "currency": "USD"}`, string(data))
+
+	var decoded Money
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, money, decoded)
+}
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+var (
+	ErrInvalidURL       = errors.New("url must be an http or https url with a host")
+	ErrInsecureURL      = errors.New("url must be an https url")
+	ErrForbiddenAddress = errors.New("url must not point to a loopback, private or link-local address")
+)
+
+// Resolver looks up the addresses of a host, *net.Resolver implements it
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// PublicIP reports whether ip can be reached by deliveries, which must not hit the bank's own network
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// URLChecker decides which urls webhooks can be registered with
+type URLChecker struct {
+	resolver     Resolver
+	requireHTTPS bool
+}
+
+// NewURLChecker returns a checker resolving hosts with resolver, plain http is only accepted when requireHTTPS is false
+func NewURLChecker(resolver Resolver, requireHTTPS bool) *URLChecker {
+	return &URLChecker{
+		resolver:     resolver,
+		requireHTTPS: requireHTTPS,
+	}
+}
+
+// Check fails when rawURL isn't a webhook url or when any address of its host isn't public
+// The host can be made to resolve elsewhere later on, so the deliverer checks the address again when dialing
+func (c *URLChecker) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	if c.requireHTTPS && u.Scheme != "https" {
+		return ErrInsecureURL
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if !PublicIP(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	addrs, err := c.resolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve the url host: %w", err)
+	}
+
+	for _, addr := range addrs {
+		if !PublicIP(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// controlAddress returns a dialer control that refuses connections to the addresses allow rejects,
+// it runs once the host is resolved, right before connecting
+func controlAddress(allow func(ip net.IP) bool) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil || !allow(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+// testResolver maps hosts to their addresses, unknown hosts don't resolve
+type testResolver map[string][]string
+
+func (r testResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+func TestPublicIP(t *testing.T) {
+	for _, ip := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		require.True(t, PublicIP(net.ParseIP(ip)), ip)
+	}
+
+	for _, ip := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "fd00::1",
+		"169.254.169.254", "fe80::1", "0.0.0.0", "::", "224.0.0.1",
+	} {
+		require.False(t, PublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestURLCheckerCheck(t *testing.T) {
+	resolver := testResolver{
+		"hooks.example.com":    {"93.184.216.34"},
+		"internal.example.com": {"10.0.0.5"},
+		"mixed.example.com":    {"93.184.216.34", "127.0.0.1"},
+	}
+
+	testCases := []struct {
+		name         string
+		url          string
+		requireHTTPS bool
+		check        func(t *testing.T, err error)
+	}{
+		{
+			name:         "PublicHost",
+			url:          "https://hooks.example.com/events",
+			requireHTTPS: true,
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:         "PublicIP",
+			url:          "https://93.184.216.34/events",
+			requireHTTPS: true,
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "PlainHTTP",
+			url:  "http://hooks.example.com/events",
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:         "PlainHTTPRequiresHTTPS",
+			url:          "http://hooks.example.com/events",
+			requireHTTPS: true,
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrInsecureURL)
+			},
+		},
+		{
+			name: "UnsupportedScheme",
+			url:  "ftp://hooks.example.com/events",
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrInvalidURL)
+			},
+		},
+		{
+			name: "NoHost",
+			url:  "https:///events",
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrInvalidURL)
+			},
+		},
+		{
+			name: "Loopback",
+			url:  "https://127.0.0.1:8080/events",
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrForbiddenAddress)
+			},
+		},
+		{
+			name: "LinkLocal",
+			url:  "http://169.254.169.254/latest/meta-data",
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrForbiddenAddress)
+			},
+		},
+		{
+			name: "IPv6Loopback",
+			url:  "https://[::1]/events",
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrForbiddenAddress)
+			},
+		},
+		{
+			name: "PrivateHost",
+			url:  "https://internal.example.com/events",
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrForbiddenAddress)
+			},
+		},
+		{
+			name: "AnyAddressPrivate",
+			url:  "https://mixed.example.com/events",
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrForbiddenAddress)
+			},
+		},
+		{
+			name: "UnknownHost",
+			url:  "https://unknown.example.com/events",
+			check: func(t *testing.T, err error) {
+				require.ErrorContains(t, err, "no such host")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewURLChecker(resolver, tc.requireHTTPS).Check(context.Background(), tc.url)
+			tc.check(t, err)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// deliveryBatchSize is how many due deliveries are claimed at once
+	deliveryBatchSize = 50
+	// deliveryTimeout bounds an attempt
+	deliveryTimeout = 10 * time.Second
+	// deliveryLease keeps claimed deliveries from other workers, the batch is attempted one delivery after another
+	deliveryLease = deliveryBatchSize*deliveryTimeout + time.Minute
+)
+
+// RetryPolicy tells how often a failed delivery is attempted again
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt
+	MaxAttempts int32
+	// InitialBackoff is doubled after every failed attempt up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Payload is the JSON body of a delivery, Data is the event of the account
+type Payload struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Deliverer posts signed webhook deliveries, any status but 2xx fails the attempt
+type Deliverer struct {
+	client *http.Client
+	policy RetryPolicy
+}
+
+// NewDeliverer returns a deliverer that only connects to public addresses,
+// whatever the host of the webhook resolves to when the delivery is made
+func NewDeliverer(policy RetryPolicy) *Deliverer {
+	return newDeliverer(policy, PublicIP)
+}
+
+func newDeliverer(policy RetryPolicy, allow func(ip net.IP) bool) *Deliverer {
+	dialer := &net.Dialer{
+		Timeout: deliveryTimeout,
+		Control: controlAddress(allow),
+	}
+
+	// a proxy would make the dialed address the proxy's, so none is used
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Deliverer{
+		client: &http.Client{Timeout: deliveryTimeout, Transport: transport},
+		policy: policy,
+	}
+}
+
+// Deliver makes a single attempt and returns the response status, 0 when no response was received
+func (d *Deliverer) Deliver(ctx context.Context, row db.ListDueWebhookDeliveriesForUpdateRow) (int32, error) {
+	delivery := row.WebhookDelivery
+
+	body, err := json.Marshal(Payload{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, row.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IDHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(row.Secret, timestamp, body))
+
+	rsp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+
+	// draining the body lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(rsp.Body, 64<<10))
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return int32(rsp.StatusCode), fmt.Errorf("webhook responded with status %d", rsp.StatusCode)
+	}
+	return int32(rsp.StatusCode), nil
+}
+
+// Backoff returns the wait after attempts failed attempts, doubling from the initial backoff up to the maximum
+func (d *Deliverer) Backoff(attempts int32) time.Duration {
+	backoff := d.policy.InitialBackoff
+	for n := int32(1); n < attempts && backoff < d.policy.MaxBackoff; n++ {
+		backoff *= 2
+	}
+
+	if backoff > d.policy.MaxBackoff {
+		return d.policy.MaxBackoff
+	}
+	return backoff
+}
+
+// DeliverDue attempts the deliveries due at now batch by batch and returns them as recorded after their attempt
+func (d *Deliverer) DeliverDue(ctx context.Context, store db.Store, now time.Time) ([]db.WebhookDelivery, error) {
+	var attempted []db.WebhookDelivery
+
+	for {
+		deliveries, err := store.DeliverWebhooksTx(ctx, db.DeliverWebhooksTxParams{
+			Now:           now,
+			BatchSize:     deliveryBatchSize,
+			LeaseDuration: deliveryLease,
+			MaxAttempts:   d.policy.MaxAttempts,
+			Backoff:       d.Backoff,
+			Deliver:       d.Deliver,
+		})
+		if err != nil {
+			return attempted, err
+		}
+
+		attempted = append(attempted, deliveries...)
+		if len(deliveries) < deliveryBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// RunDeliveries attempts the due deliveries every interval until ctx is done
+func RunDeliveries(ctx context.Context, store db.Store, deliverer *Deliverer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := deliverer.DeliverDue(ctx, store, time.Now()); err != nil && ctx.Err() == nil {
+				log.Println("failed to deliver webhooks:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vadym-98/simple_bank/db/mock"
+	db "github.com/vadym-98/simple_bank/db/sqlc"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     5 * time.Second,
+}
+
+func dueDelivery(url string) db.ListDueWebhookDeliveriesForUpdateRow {
+	return db.ListDueWebhookDeliveriesForUpdateRow{
+		WebhookDelivery: db.WebhookDelivery{
+			ID:        7,
+			WebhookID: 3,
+			EventType: db.WebhookEventTransferIn,
+			Payload:   json.RawMessage(`{"account_id":1,"amount":10}`),
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+		},
+		Url:    url,
+		Secret: "secret",
+	}
+}
+
+// newTestDeliverer connects to any address, test servers listen on loopback
+func newTestDeliverer() *Deliverer {
+	return newDeliverer(testPolicy, func(net.IP) bool { return true })
+}
+
+func TestDeliver(t *testing.T) {
+	var received Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		require.True(t, Verify("secret", timestamp, body, r.Header.Get(SignatureHeader)))
+		require.Equal(t, "7", r.Header.Get(IDHeader))
+		require.Equal(t, db.WebhookEventTransferIn, r.Header.Get(EventHeader))
+
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	row := dueDelivery(server.URL)
+	status, err := newTestDeliverer().Deliver(context.Background(), row)
+	require.NoError(t, err)
+	require.Equal(t, int32(http.StatusAccepted), status)
+
+	require.Equal(t, row.WebhookDelivery.ID, received.ID)
+	require.Equal(t, row.WebhookDelivery.EventType, received.Type)
+	require.True(t, row.WebhookDelivery.CreatedAt.Equal(received.CreatedAt))
+	require.JSONEq(t, string(row.WebhookDelivery.Payload), string(received.Data))
+}
+
+func TestDeliverFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	deliverer := newTestDeliverer()
+	status, err := deliverer.Deliver(context.Background(), dueDelivery(server.URL))
+	require.ErrorContains(t, err, "500")
+	require.Equal(t, int32(http.StatusInternalServerError), status)
+
+	// without a response there is no status
+	server.Close()
+	status, err = deliverer.Deliver(context.Background(), dueDelivery(server.URL))
+	require.Error(t, err)
+	require.Zero(t, status)
+}
+
+func TestDeliverForbiddenAddress(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// the host could have resolved to a public address when the webhook was registered
+	status, err := NewDeliverer(testPolicy).Deliver(context.Background(), dueDelivery(server.URL))
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+	require.Zero(t, status)
+	require.Zero(t, calls)
+}
+
+func TestBackoff(t *testing.T) {
+	deliverer := NewDeliverer(testPolicy)
+
+	require.Equal(t, time.Second, deliverer.Backoff(1))
+	require.Equal(t, 2*time.Second, deliverer.Backoff(2))
+	require.Equal(t, 4*time.Second, deliverer.Backoff(3))
+	require.Equal(t, 5*time.Second, deliverer.Backoff(4))
+	require.Equal(t, 5*time.Second, deliverer.Backoff(40))
+}
+
+func TestDeliverDue(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	row := dueDelivery(server.URL)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeliverWebhooksTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.DeliverWebhooksTxParams) ([]db.WebhookDelivery, error) {
+			require.Equal(t, now, arg.Now)
+			require.Equal(t, testPolicy.MaxAttempts, arg.MaxAttempts)
+			require.Greater(t, arg.LeaseDuration, deliveryBatchSize*deliveryTimeout)
+			require.Equal(t, 2*time.Second, arg.Backoff(2))
+
+			status, err := arg.Deliver(ctx, row)
+			require.NoError(t, err)
+
+			delivery := row.WebhookDelivery
+			delivery.Status = db.WebhookDeliveryStatusSucceeded
+			delivery.ResponseStatus = status
+			return []db.WebhookDelivery{delivery}, nil
+		})
+
+	deliveries, err := newTestDeliverer().DeliverDue(context.Background(), store, now)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, int32(http.StatusOK), deliveries[0].ResponseStatus)
+	require.Equal(t, 1, calls)
+}
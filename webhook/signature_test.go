@@ -0,0 +1,25 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 64)
+
+	body := []byte(`{"id":1}`)
+	signature := Sign(secret, 1700000000, body)
+	require.True(t, strings.HasPrefix(signature, "sha256="))
+	require.True(t, Verify(secret, 1700000000, body, signature))
+
+	// the signature covers the secret, the timestamp and the body
+	otherSecret, err := NewSecret()
+	require.NoError(t, err)
+	require.False(t, Verify(otherSecret, 1700000000, body, signature))
+	require.False(t, Verify(secret, 1700000001, body, signature))
+	require.False(t, Verify(secret, 1700000000, []byte(`{"id":2}`), signature))
+}